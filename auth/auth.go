@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/svenschwermer/gcal-notify/browser"
@@ -20,12 +21,25 @@ import (
 	"google.golang.org/api/calendar/v3"
 )
 
+// tokenInfoURL is the endpoint reporting the scopes granted to an access
+// token.
+var tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// Scope returns the OAuth2 scope required by the configured features. Write
+// access is only requested for RSVP actions and comments about running late.
+func Scope() string {
+	if config.Cfg.AllowRSVP || config.Cfg.RunningLate.Via == "comment" {
+		return calendar.CalendarEventsScope
+	}
+	return calendar.CalendarEventsReadonlyScope
+}
+
 func configFromDisk() (*oauth2.Config, error) {
 	clientSecretJSON, err := os.ReadFile(config.Cfg.ClientSecretPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read client secret file: %w", err)
 	}
-	config, err := google.ConfigFromJSON(clientSecretJSON, calendar.CalendarEventsReadonlyScope)
+	config, err := google.ConfigFromJSON(clientSecretJSON, Scope())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse client secret file to config: %w", err)
 	}
@@ -63,23 +77,25 @@ func GetTokenSourceFromWeb(ctx context.Context) (oauth2.TokenSource, error) {
 	})
 	go http.Serve(lis, nil)
 
-	authURL := config.AuthCodeURL("", oauth2.AccessTypeOffline)
+	// prompt for consent even if authorized before, so that added scopes are
+	// granted
+	authURL := config.AuthCodeURL("", oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
 	if !browser.Open(authURL) {
 		fmt.Printf("Go to the following link in your browser:\n\n%v\n\n", authURL)
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	timeout, cancel := context.WithTimeout(ctx, time.Minute)
 	defer cancel()
 	select {
 	case code := <-authCode:
-		tok, err := config.Exchange(ctx, code)
+		tok, err := config.Exchange(timeout, code)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve token from web: %w", err)
 		}
 		return config.TokenSource(ctx, tok), nil
 	case err := <-authError:
 		return nil, fmt.Errorf("oauth2 exchange failed: %w", err)
-	case <-ctx.Done():
+	case <-timeout.Done():
 		return nil, errors.New("oauth2 exchange timed out")
 	}
 }
@@ -100,6 +116,39 @@ func GetTokenSourceFromDisk(ctx context.Context) (oauth2.TokenSource, error) {
 	return cfg.TokenSource(ctx, tok), nil
 }
 
+// HasScope returns whether the access token of ts was granted scope.
+func HasScope(ctx context.Context, ts oauth2.TokenSource, scope string) (bool, error) {
+	tok, err := ts.Token()
+	if err != nil {
+		return false, fmt.Errorf("failed to get token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		tokenInfoURL+"?access_token="+url.QueryEscape(tok.AccessToken), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query token info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("token info request failed with status %s", resp.Status)
+	}
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return false, fmt.Errorf("failed to decode token info: %w", err)
+	}
+	for _, s := range strings.Fields(info.Scope) {
+		if s == scope {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func WriteTokenToDisk(ts oauth2.TokenSource, fatal bool) {
 	if err := writeTokenToDisk(ts); err != nil {
 		logger := log.Printf
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestHasScope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ya29.test", r.URL.Query().Get("access_token"))
+		rw.Write([]byte(`{"scope":"openid https://www.googleapis.com/auth/calendar.events.readonly","expires_in":"3599"}`))
+	}))
+	t.Cleanup(srv.Close)
+	prev := tokenInfoURL
+	tokenInfoURL = srv.URL
+	t.Cleanup(func() { tokenInfoURL = prev })
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.test"})
+	ok, err := HasScope(context.Background(), ts, calendar.CalendarEventsReadonlyScope)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = HasScope(context.Background(), ts, calendar.CalendarEventsScope)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
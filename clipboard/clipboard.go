@@ -0,0 +1,54 @@
+// Package clipboard provides utilities for copying text to the user's
+// clipboard.
+package clipboard
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+)
+
+// Commands returns a list of possible commands to use to copy text which is
+// passed on stdin.
+func Commands() [][]string {
+	var cmds [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		cmds = append(cmds, []string{"pbcopy"})
+	case "windows":
+		cmds = append(cmds, []string{"clip"})
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			cmds = append(cmds, []string{"wl-copy"})
+		}
+		if os.Getenv("DISPLAY") != "" {
+			cmds = append(cmds,
+				[]string{"xclip", "-selection", "clipboard"},
+				[]string{"xsel", "--clipboard", "--input"},
+			)
+		}
+	}
+	return cmds
+}
+
+// Copy tries to copy text to the clipboard and reports whether it succeeded.
+func Copy(text string) bool {
+	for _, args := range Commands() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		config.Debug.Printf("clipboard: %v", cmd.Args)
+		err := cmd.Run()
+		cancel()
+		if err == nil {
+			return true
+		}
+	}
+	config.Debug.Printf("clipboard: copying failed")
+	return false
+}
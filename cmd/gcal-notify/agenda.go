@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/auth"
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/control"
+	"github.com/svenschwermer/gcal-notify/events"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+const (
+	formatHuman   = "human"
+	formatJSON    = "json"
+	formatOneline = "oneline"
+)
+
+// eventFlags parses the flags of commands listing events.
+func eventFlags(name string, args []string) (format string, err error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&format, "format", formatHuman, "Output format: human, json or oneline")
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("did not expect argument, %d provided", fs.NArg())
+	}
+	switch format {
+	case formatHuman, formatJSON, formatOneline:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format %q", format)
+}
+
+func runNext(ctx context.Context, args []string) error {
+	format, err := eventFlags("next", args)
+	if err != nil {
+		return err
+	}
+	list, err := upcoming(ctx, time.Now().Add(config.Cfg.LookaheadInterval.D))
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	var next []control.Event
+	for _, e := range list {
+		if !e.AllDay && e.Start >= now {
+			next = append(next, e)
+			break
+		}
+	}
+	return printEvents(os.Stdout, next, format, time.Now())
+}
+
+func runAgenda(ctx context.Context, args []string) error {
+	format, err := eventFlags("agenda", args)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	list, err := upcoming(ctx, endOfDay)
+	if err != nil {
+		return err
+	}
+	var agenda []control.Event
+	for _, e := range list {
+		if e.Start < endOfDay.Unix() {
+			agenda = append(agenda, e)
+		}
+	}
+	return printEvents(os.Stdout, agenda, format, time.Now())
+}
+
+// upcoming returns the events that have not ended yet, from the running daemon
+// or, if not running, directly from Google Calendar until the given time.
+func upcoming(ctx context.Context, until time.Time) ([]control.Event, error) {
+	c, err := control.Dial()
+	if err == nil {
+		defer c.Close()
+		return c.ListUpcoming(ctx)
+	}
+	config.Debug.Printf("Querying Google Calendar directly: %v", err)
+	return queryCalendars(ctx, until)
+}
+
+// queryCalendars lists the events of the configured calendars that have not
+// ended yet and start before until, ordered by start.
+func queryCalendars(ctx context.Context, until time.Time) ([]control.Event, error) {
+	if len(config.Cfg.Calendars) == 0 {
+		return nil, fmt.Errorf("no calendar ID configured")
+	}
+	ts, err := auth.GetTokenSourceFromDisk(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth token from disk: %w", err)
+	}
+	svc, err := calendar.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, ts)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve Calendar client: %w", err)
+	}
+
+	var list []control.Event
+	for i := range config.Cfg.Calendars {
+		cal := &config.Cfg.Calendars[i]
+		calList, err := events.List(ctx, svc, cal, until)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events of %s: %w", cal.ID, err)
+		}
+		for _, e := range calList {
+			list = append(list, control.NewEvent(e))
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Start < list[j].Start })
+	return list, nil
+}
+
+type eventJSON struct {
+	CalendarID    string    `json:"calendarId"`
+	ID            string    `json:"id"`
+	Summary       string    `json:"summary"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	AllDay        bool      `json:"allDay"`
+	ConferenceURL string    `json:"conferenceUrl,omitempty"`
+	Link          string    `json:"link,omitempty"`
+	Location      string    `json:"location,omitempty"`
+}
+
+// printEvents prints list in the given format, describing the start of the
+// events relative to now. Nothing is printed in the oneline format if list is
+// empty.
+func printEvents(w io.Writer, list []control.Event, format string, now time.Time) error {
+	switch format {
+	case formatJSON:
+		out := make([]eventJSON, len(list))
+		for i, e := range list {
+			out[i] = eventJSON{
+				CalendarID:    e.CalendarID,
+				ID:            e.ID,
+				Summary:       e.Summary,
+				Start:         time.Unix(e.Start, 0),
+				End:           time.Unix(e.End, 0),
+				AllDay:        e.AllDay,
+				ConferenceURL: e.ConferenceURL,
+				Link:          e.Link,
+				Location:      e.Location,
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case formatOneline:
+		if len(list) == 0 {
+			return nil
+		}
+		parts := make([]string, len(list))
+		for i, e := range list {
+			parts[i] = fmt.Sprintf("%s %s", when(e, now), e.Summary)
+		}
+		_, err := fmt.Fprintln(w, strings.Join(parts, " · "))
+		return err
+	}
+
+	if len(list) == 0 {
+		_, err := fmt.Fprintln(w, "No upcoming events")
+		return err
+	}
+	for _, e := range list {
+		start, end := time.Unix(e.Start, 0), time.Unix(e.End, 0)
+		span := start.Format("Mon 2 Jan 15:04") + " – " + end.Format("15:04")
+		if e.AllDay {
+			span = start.Format("Mon 2 Jan") + " (all day)"
+		}
+		fmt.Fprintf(w, "%s  %s (%s)\n", span, e.Summary, when(e, now))
+		if e.ConferenceURL != "" {
+			fmt.Fprintf(w, "    Join: %s\n", e.ConferenceURL)
+		}
+		if e.Location != "" && e.Location != e.ConferenceURL {
+			fmt.Fprintf(w, "    Location: %s\n", e.Location)
+		}
+	}
+	return nil
+}
+
+// when describes the start of e relative to now, e.g. "in 5 min".
+func when(e control.Event, now time.Time) string {
+	start, end := time.Unix(e.Start, 0), time.Unix(e.End, 0)
+	switch {
+	case e.AllDay && !now.Before(start):
+		return "today"
+	case !now.Before(end):
+		return "ended"
+	case !now.Before(start):
+		return "now, until " + end.Format("15:04")
+	case start.Sub(now) < time.Hour:
+		return fmt.Sprintf("in %d min", int(start.Sub(now).Minutes()+0.5))
+	default:
+		return start.Format("15:04")
+	}
+}
+
+// withDaemon connects to the running daemon to run f.
+func withDaemon(ctx context.Context, f func(*control.Client) error) error {
+	c, err := control.Dial()
+	if errors.Is(err, control.ErrNotRunning) {
+		return fmt.Errorf("%w, start it with\n  %s run", err, os.Args[0])
+	}
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return f(c)
+}
+
+func runPause(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected duration, e.g. 1h")
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid duration %q", args[0])
+	}
+	return withDaemon(ctx, func(c *control.Client) error {
+		if err := c.Pause(ctx, d); err != nil {
+			return err
+		}
+		log.Printf("Reminders paused until %s", time.Now().Add(d).Format("15:04"))
+		return nil
+	})
+}
+
+func runResume(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("did not expect argument, %d provided", len(args))
+	}
+	return withDaemon(ctx, func(c *control.Client) error {
+		return c.Resume(ctx)
+	})
+}
+
+func runRefresh(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("did not expect argument, %d provided", len(args))
+	}
+	return withDaemon(ctx, func(c *control.Client) error {
+		return c.Refresh(ctx)
+	})
+}
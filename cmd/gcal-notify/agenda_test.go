@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/control"
+)
+
+func TestEventFlags(t *testing.T) {
+	format, err := eventFlags("next", nil)
+	require.NoError(t, err)
+	assert.Equal(t, formatHuman, format)
+
+	format, err = eventFlags("next", []string{"-format", "oneline"})
+	require.NoError(t, err)
+	assert.Equal(t, formatOneline, format)
+
+	_, err = eventFlags("next", []string{"-format", "xml"})
+	assert.EqualError(t, err, `unknown format "xml"`)
+	_, err = eventFlags("next", []string{"tomorrow"})
+	assert.EqualError(t, err, "did not expect argument, 1 provided")
+}
+
+func TestWhen(t *testing.T) {
+	now := time.Date(2030, 5, 12, 14, 0, 0, 0, time.Local)
+	event := func(start, end time.Duration) control.Event {
+		return control.Event{Start: now.Add(start).Unix(), End: now.Add(end).Unix()}
+	}
+	holiday := control.Event{
+		Start:  time.Date(2030, 5, 12, 0, 0, 0, 0, time.Local).Unix(),
+		End:    time.Date(2030, 5, 13, 0, 0, 0, 0, time.Local).Unix(),
+		AllDay: true,
+	}
+
+	tests := []struct {
+		name  string
+		event control.Event
+		want  string
+	}{
+		{"all day", holiday, "today"},
+		{"ended", event(-time.Hour, -time.Minute), "ended"},
+		{"running", event(-time.Minute, 29*time.Minute), "now, until 14:29"},
+		{"soon", event(4*time.Minute+40*time.Second, time.Hour), "in 5 min"},
+		{"rounded down", event(4*time.Minute+20*time.Second, time.Hour), "in 4 min"},
+		{"later", event(2*time.Hour, 3*time.Hour), "16:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, when(tt.event, now))
+		})
+	}
+}
+
+func TestPrintEvents(t *testing.T) {
+	now := time.Date(2030, 5, 12, 14, 0, 0, 0, time.Local) // a Sunday
+	standup := control.Event{
+		CalendarID:    "me@example.com",
+		ID:            "standup",
+		Summary:       "Standup",
+		Start:         now.Add(10 * time.Minute).Unix(),
+		End:           now.Add(25 * time.Minute).Unix(),
+		ConferenceURL: "https://meet.google.com/abc-defg-hij",
+	}
+	retro := control.Event{
+		CalendarID: "me@example.com",
+		ID:         "retro",
+		Summary:    "Retro",
+		Start:      now.Add(2 * time.Hour).Unix(),
+		End:        now.Add(3 * time.Hour).Unix(),
+		Location:   "Room 1",
+	}
+
+	tests := []struct {
+		name   string
+		list   []control.Event
+		format string
+		want   string
+	}{
+		{
+			name:   "human",
+			list:   []control.Event{standup, retro},
+			format: formatHuman,
+			want: "Sun 12 May 14:10 – 14:25  Standup (in 10 min)\n" +
+				"    Join: https://meet.google.com/abc-defg-hij\n" +
+				"Sun 12 May 16:00 – 17:00  Retro (16:00)\n" +
+				"    Location: Room 1\n",
+		},
+		{
+			name:   "human without events",
+			format: formatHuman,
+			want:   "No upcoming events\n",
+		},
+		{
+			name:   "oneline",
+			list:   []control.Event{standup, retro},
+			format: formatOneline,
+			want:   "in 10 min Standup · 16:00 Retro\n",
+		},
+		{
+			name:   "oneline without events",
+			format: formatOneline,
+			want:   "",
+		},
+		{
+			name:   "json without events",
+			format: formatJSON,
+			want:   "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, printEvents(&b, tt.list, tt.format, now))
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func TestPrintEventsJSON(t *testing.T) {
+	start := time.Date(2030, 5, 12, 14, 0, 0, 0, time.Local)
+	var b bytes.Buffer
+	require.NoError(t, printEvents(&b, []control.Event{{
+		CalendarID: "me@example.com",
+		ID:         "standup",
+		Summary:    "Standup",
+		Start:      start.Unix(),
+		End:        start.Add(15 * time.Minute).Unix(),
+	}}, formatJSON, start))
+
+	var out []map[string]any
+	require.NoError(t, json.Unmarshal(b.Bytes(), &out))
+	require.Len(t, out, 1)
+	assert.Equal(t, "standup", out[0]["id"])
+	assert.Equal(t, false, out[0]["allDay"])
+	assert.Equal(t, start.Format(time.RFC3339), out[0]["start"])
+	assert.NotContains(t, out[0], "conferenceUrl")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/svenschwermer/gcal-notify/auth"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func runAuth(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("did not expect argument, %d provided", len(args))
+	}
+
+	ts, err := auth.GetTokenSourceFromWeb(ctx)
+	if err != nil {
+		return fmt.Errorf("authorization failed: %w", err)
+	}
+	auth.WriteTokenToDisk(ts, true)
+	fmt.Printf("Token written to %s\n", config.Cfg.TokenPath)
+
+	tok, err := ts.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+	if scope, ok := tok.Extra("scope").(string); ok {
+		fmt.Println("Granted scopes:")
+		for _, s := range strings.Fields(scope) {
+			fmt.Printf("  %s\n", s)
+		}
+	}
+
+	svc, err := calendar.NewService(ctx, option.WithTokenSource(ts))
+	if err != nil {
+		return fmt.Errorf("unable to retrieve Calendar client: %w", err)
+	}
+	// The summary of the primary calendar is the account's email address.
+	primary, err := svc.Events.List("primary").MaxResults(1).Context(ctx).Do()
+	if err != nil {
+		log.Printf("Failed to query primary calendar: %v", err)
+		return nil
+	}
+	fmt.Printf("Account: %s (time zone %s)\n", primary.Summary, primary.TimeZone)
+	return nil
+}
@@ -4,64 +4,73 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sort"
 
-	"github.com/svenschwermer/gcal-notify/auth"
 	"github.com/svenschwermer/gcal-notify/config"
-	"github.com/svenschwermer/gcal-notify/events"
-	"github.com/svenschwermer/gcal-notify/location"
-	"github.com/svenschwermer/gcal-notify/slack"
-	"golang.org/x/oauth2"
-	"golang.org/x/sync/errgroup"
-	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 )
 
 var cfgFilePath = flag.String("config", config.DefaultPath, "Configuration file path")
 
+type command struct {
+	help string
+	run  func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"agenda":  {"List today's remaining events [-format human|json|oneline]", runAgenda},
+	"auth":    {"Authorize access to Google Calendar and store the token", runAuth},
+	"next":    {"Show the next event [-format human|json|oneline]", runNext},
+	"pause":   {"Hold back reminders of the running service for a duration", runPause},
+	"refresh": {"Make the running service poll the calendars right away", runRefresh},
+	"resume":  {"Resume reminders of the running service", runResume},
+	"run":     {"Run the notification service (default)", runDaemon},
+	"status":  {"Print the running or next event for status bars [-follow] [-format waybar|i3bar]", runStatus},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [args]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-8s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
 func main() {
 	log.SetFlags(0)
 
+	flag.Usage = usage
 	flag.Parse()
 	config.Parse(*cfgFilePath)
 
-	if flag.NArg() > 0 {
-		log.Fatalf("Did not expect argument, %d provided", flag.NArg())
+	name, args := "run", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		log.Printf("Unknown command %q", name)
+		flag.Usage()
+		os.Exit(2)
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	ts, err := auth.GetTokenSourceFromDisk(ctx)
-	if err != nil {
-		log.Fatalf("Failed to read auth token from disk: %v\nConsider running\n  %s auth",
-			err, os.Args[0])
-	}
-	client := oauth2.NewClient(ctx, ts)
-	defer auth.WriteTokenToDisk(ts, false)
-
-	svc, err := calendar.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		log.Fatalf("Unable to retrieve Calendar client: %v", err)
-	}
-
-	n, err := events.NewNotifier(svc, config.Cfg.CalendarID)
-	if err != nil {
-		log.Fatalf("Unable to initialize notifier: %v", err)
-	}
-
-	slack, err := slack.NewClient()
-	if err != nil {
-		log.Fatalf("Unable to initialize slack client: %v", err)
-	}
-	loc := location.NewBot(svc, config.Cfg.CalendarID, slack)
-
-	g, ctx := errgroup.WithContext(ctx)
-	g.Go(func() error { return n.Poll(ctx) })
-	g.Go(func() error { return loc.Poll(ctx) })
-	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+	err := cmd.run(ctx, args)
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, flag.ErrHelp):
+	default:
 		log.Fatal(err)
 	}
 }
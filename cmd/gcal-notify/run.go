@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/svenschwermer/gcal-notify/auth"
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/control"
+	"github.com/svenschwermer/gcal-notify/desktop"
+	"github.com/svenschwermer/gcal-notify/events"
+	"github.com/svenschwermer/gcal-notify/location"
+	"github.com/svenschwermer/gcal-notify/push"
+	"github.com/svenschwermer/gcal-notify/slack"
+	"github.com/svenschwermer/gcal-notify/terminal"
+	"github.com/svenschwermer/gcal-notify/webhook"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+func runDaemon(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("did not expect argument, %d provided", len(args))
+	}
+	if len(config.Cfg.Calendars) == 0 {
+		return fmt.Errorf("no calendar ID configured")
+	}
+
+	ts, err := auth.GetTokenSourceFromDisk(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read auth token from disk: %w\nConsider running\n  %s auth",
+			err, os.Args[0])
+	}
+	client := oauth2.NewClient(ctx, ts)
+	defer auth.WriteTokenToDisk(ts, false)
+
+	svc, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return fmt.Errorf("unable to retrieve Calendar client: %w", err)
+	}
+
+	n := events.NewNotifier(svc, config.Cfg.Calendars)
+	writable := false
+	if auth.Scope() == calendar.CalendarEventsScope {
+		if ok, err := auth.HasScope(ctx, ts, calendar.CalendarEventsScope); err != nil {
+			log.Printf("Failed to check granted scopes, features requiring write access disabled: %v", err)
+		} else if !ok {
+			log.Printf("Write access to events has not been granted, features requiring it are disabled. Consider running\n  %s auth",
+				os.Args[0])
+		} else {
+			writable = true
+		}
+	}
+	if config.Cfg.AllowRSVP && writable {
+		n.EnableRSVP()
+	}
+	useTerminal := config.Cfg.Terminal.Enabled
+	if sessionBus, err := desktop.SessionBus(); err != nil {
+		log.Printf("Desktop notifications unavailable, writing to terminal: %v", err)
+		useTerminal = true
+	} else {
+		defer sessionBus.Close()
+		if d, err := desktop.New(sessionBus, n); err != nil {
+			log.Printf("Desktop notifications unavailable, writing to terminal: %v", err)
+			useTerminal = true
+		} else {
+			n.AddSink(d)
+		}
+		c, err := control.Export(sessionBus, n)
+		if err != nil {
+			return fmt.Errorf("unable to export control interface: %w", err)
+		}
+		n.AddObserver(c)
+	}
+	if useTerminal {
+		t, err := terminal.New()
+		if err != nil {
+			return fmt.Errorf("unable to initialize terminal notifications: %w", err)
+		}
+		n.AddSink(t)
+	}
+
+	slackClient, err := slack.NewClient()
+	if err != nil {
+		return fmt.Errorf("unable to initialize slack client: %w", err)
+	}
+	switch config.Cfg.RunningLate.Via {
+	case "slack":
+		n.SetMessenger(slackClient)
+	case "comment":
+		if writable {
+			n.SetMessenger(events.NewCommentMessenger(svc))
+		} else {
+			log.Printf("Running late comments require write access to events and are disabled")
+		}
+	}
+	// working locations are taken from the first (i.e. personal) calendar
+	loc := location.NewBot(svc, config.Cfg.Calendars[0].ID, slackClient)
+
+	var webhooks []*webhook.Sink
+	for _, cfg := range config.Cfg.Webhooks {
+		w, err := webhook.New(cfg)
+		if err != nil {
+			return err
+		}
+		n.AddSink(w)
+		webhooks = append(webhooks, w)
+	}
+
+	var watcher *push.Watcher
+	if config.Cfg.Push.URL != "" {
+		watcher, err = push.NewWatcher(svc, config.Cfg.Calendars, n)
+		if err != nil {
+			return fmt.Errorf("unable to initialize push watcher: %w", err)
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if config.Cfg.SlackReminders {
+		s := slack.NewSink(slackClient)
+		n.AddSink(s)
+		g.Go(func() error { return s.Run(ctx) })
+	}
+	for _, w := range webhooks {
+		w := w
+		g.Go(func() error { return w.Run(ctx) })
+	}
+	g.Go(func() error { return n.Poll(ctx) })
+	g.Go(func() error { return loc.Poll(ctx) })
+	if watcher != nil {
+		g.Go(func() error { return watcher.Run(ctx) })
+	}
+	return g.Wait()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/control"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+const (
+	formatWaybar = "waybar"
+	formatI3bar  = "i3bar"
+
+	// soonThreshold is the time before the start of the next event from which
+	// it is shown instead of a running one.
+	soonThreshold = 5 * time.Minute
+	// directRefresh is the interval at which Google Calendar is queried if the
+	// service is not running.
+	directRefresh = 5 * time.Minute
+)
+
+// status describes the running or next event in a status bar, see waybar's
+// custom module.
+type status struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+}
+
+func runStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	follow := fs.Bool("follow", false, "Keep printing updates, every minute and whenever events change")
+	format := fs.String("format", formatWaybar, "Output format: waybar or i3bar")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("did not expect argument, %d provided", fs.NArg())
+	}
+	var print func(w io.Writer, st status) error
+	switch *format {
+	case formatWaybar:
+		print = printWaybar
+	case formatI3bar:
+		print = printI3bar
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	src := &statusSource{subscribe: *follow}
+	defer src.close()
+	if !*follow {
+		list, paused, err := src.fetch(ctx)
+		if err != nil {
+			return err
+		}
+		return print(os.Stdout, newStatus(list, paused, time.Now()))
+	}
+
+	if *format == formatI3bar {
+		// header and start of the infinite array of status lines, see
+		// https://i3wm.org/docs/i3bar-protocol.html
+		fmt.Println(`{"version":1}`)
+		fmt.Println("[")
+	}
+	for {
+		list, paused, err := src.fetch(ctx)
+		if err != nil {
+			// keep following, e.g. while offline
+			config.Debug.Printf("Failed to get events: %v", err)
+		}
+		if err := print(os.Stdout, newStatus(list, paused, time.Now())); err != nil {
+			return err
+		}
+		if *format == formatI3bar {
+			fmt.Println(",")
+		}
+
+		// update at the start of the next minute
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-timer.C:
+		case <-src.changes:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// statusSource gets the upcoming events from the running service or, if not
+// running, directly from Google Calendar.
+type statusSource struct {
+	subscribe bool
+	c         *control.Client
+	changes   <-chan *dbus.Signal // nil unless subscribed
+
+	cached  []control.Event // from Google Calendar
+	fetched time.Time
+}
+
+func (s *statusSource) fetch(ctx context.Context) ([]control.Event, time.Time, error) {
+	if s.c == nil {
+		if c, err := control.Dial(); err == nil {
+			s.c = c
+			if s.subscribe {
+				if s.changes, err = c.Subscribe(); err != nil {
+					config.Debug.Printf("Falling back to updates every minute: %v", err)
+				}
+			}
+		}
+	}
+	if s.c != nil {
+		list, err := s.c.ListUpcoming(ctx)
+		if err == nil {
+			paused, err := s.c.PausedUntil(ctx)
+			return list, paused, err
+		}
+		config.Debug.Printf("Querying Google Calendar directly: %v", err)
+		s.close()
+	}
+
+	if time.Since(s.fetched) >= directRefresh {
+		list, err := queryCalendars(ctx, time.Now().Add(config.Cfg.LookaheadInterval.D))
+		if err != nil {
+			return s.cached, time.Time{}, err
+		}
+		s.cached, s.fetched = list, time.Now()
+	}
+	return s.cached, time.Time{}, nil
+}
+
+func (s *statusSource) close() {
+	if s.c != nil {
+		s.c.Close()
+		s.c, s.changes = nil, nil
+	}
+}
+
+// newStatus describes the running event or, if none or the next one starts
+// soon, the next one. The tooltip lists today's remaining events.
+func newStatus(list []control.Event, pausedUntil, now time.Time) status {
+	var running, next *control.Event
+	var agenda []string
+	for i := range list {
+		e := &list[i]
+		start, end := time.Unix(e.Start, 0), time.Unix(e.End, 0)
+		if !end.After(now) {
+			continue
+		}
+		if events.SameDay(start, now) || start.Before(now) {
+			span := start.Format("15:04") + "–" + end.Format("15:04")
+			if e.AllDay {
+				span = "all day"
+			}
+			agenda = append(agenda, span+"  "+e.Summary)
+		}
+		if e.AllDay {
+			continue
+		}
+		if !start.After(now) {
+			if running == nil {
+				running = e
+			}
+		} else if next == nil {
+			next = e
+		}
+	}
+
+	st := status{Class: "none", Tooltip: strings.Join(agenda, "\n")}
+	if len(agenda) == 0 {
+		st.Tooltip = "No more events today"
+	}
+	switch {
+	case next != nil && (running == nil || time.Unix(next.Start, 0).Sub(now) <= soonThreshold):
+		start := time.Unix(next.Start, 0)
+		until := start.Sub(now)
+		st.Class = "upcoming"
+		if until <= soonThreshold {
+			st.Class = "soon"
+		}
+		switch {
+		case until < time.Hour:
+			st.Text = fmt.Sprintf("%s in %dm", next.Summary, int((until+time.Minute-1)/time.Minute))
+			st.Percentage = int(100 - until*100/time.Hour)
+		case events.SameDay(start, now):
+			st.Text = fmt.Sprintf("%s at %s", next.Summary, start.Format("15:04"))
+		default:
+			st.Text = fmt.Sprintf("%s %s", next.Summary, start.Format("Mon 15:04"))
+		}
+	case running != nil:
+		start, end := time.Unix(running.Start, 0), time.Unix(running.End, 0)
+		st.Class = "running"
+		st.Text = fmt.Sprintf("%s until %s", running.Summary, end.Format("15:04"))
+		st.Percentage = int(now.Sub(start) * 100 / end.Sub(start))
+	}
+	if !pausedUntil.IsZero() {
+		st.Class = "paused"
+		st.Tooltip = fmt.Sprintf("Reminders paused until %s\n%s", pausedUntil.Format("15:04"), st.Tooltip)
+	}
+	return st
+}
+
+func printWaybar(w io.Writer, st status) error {
+	return json.NewEncoder(w).Encode(st)
+}
+
+// printI3bar prints a status line of the i3bar protocol, which is also
+// understood by e.g. swaybar and i3status-rust.
+func printI3bar(w io.Writer, st status) error {
+	block := struct {
+		Name     string `json:"name"`
+		FullText string `json:"full_text"`
+		Urgent   bool   `json:"urgent,omitempty"`
+	}{"gcal-notify", st.Text, st.Class == "soon"}
+	b, err := json.Marshal([]any{block})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
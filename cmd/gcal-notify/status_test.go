@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/svenschwermer/gcal-notify/control"
+)
+
+func TestNewStatus(t *testing.T) {
+	now := time.Date(2030, 5, 12, 14, 0, 0, 0, time.Local) // a Sunday
+	event := func(summary string, start, end time.Duration) control.Event {
+		return control.Event{Summary: summary, Start: now.Add(start).Unix(), End: now.Add(end).Unix()}
+	}
+	holiday := control.Event{
+		Summary: "Holiday",
+		Start:   time.Date(2030, 5, 12, 0, 0, 0, 0, time.Local).Unix(),
+		End:     time.Date(2030, 5, 13, 0, 0, 0, 0, time.Local).Unix(),
+		AllDay:  true,
+	}
+
+	tests := []struct {
+		name   string
+		events []control.Event
+		paused time.Time
+		want   status
+	}{
+		{
+			name: "no events",
+			want: status{Class: "none", Tooltip: "No more events today"},
+		},
+		{
+			name:   "ended and all-day events",
+			events: []control.Event{event("Standup", -time.Hour, -30*time.Minute), holiday},
+			want:   status{Class: "none", Tooltip: "all day  Holiday"},
+		},
+		{
+			name:   "upcoming",
+			events: []control.Event{event("Standup", 11*time.Minute+30*time.Second, 26*time.Minute)},
+			want:   status{Text: "Standup in 12m", Tooltip: "14:11–14:26  Standup", Class: "upcoming", Percentage: 81},
+		},
+		{
+			name:   "soon",
+			events: []control.Event{event("Standup", 3*time.Minute, 18*time.Minute)},
+			want:   status{Text: "Standup in 3m", Tooltip: "14:03–14:18  Standup", Class: "soon", Percentage: 95},
+		},
+		{
+			name:   "rounded up",
+			events: []control.Event{event("Standup", 30*time.Second, 15*time.Minute)},
+			want:   status{Text: "Standup in 1m", Tooltip: "14:00–14:15  Standup", Class: "soon", Percentage: 100},
+		},
+		{
+			name:   "later today",
+			events: []control.Event{event("Retro", 2*time.Hour, 3*time.Hour)},
+			want:   status{Text: "Retro at 16:00", Tooltip: "16:00–17:00  Retro", Class: "upcoming"},
+		},
+		{
+			name:   "tomorrow",
+			events: []control.Event{event("Retro", 19*time.Hour, 20*time.Hour)},
+			want:   status{Text: "Retro Mon 09:00", Tooltip: "No more events today", Class: "upcoming"},
+		},
+		{
+			name:   "running",
+			events: []control.Event{holiday, event("Planning", -30*time.Minute, 30*time.Minute), event("Review", 20*time.Minute, time.Hour)},
+			want: status{
+				Text:       "Planning until 14:30",
+				Tooltip:    "all day  Holiday\n13:30–14:30  Planning\n14:20–15:00  Review",
+				Class:      "running",
+				Percentage: 50,
+			},
+		},
+		{
+			name:   "running with next soon",
+			events: []control.Event{event("Planning", -30*time.Minute, 30*time.Minute), event("Review", 4*time.Minute, time.Hour)},
+			want: status{
+				Text:       "Review in 4m",
+				Tooltip:    "13:30–14:30  Planning\n14:04–15:00  Review",
+				Class:      "soon",
+				Percentage: 94,
+			},
+		},
+		{
+			name:   "paused",
+			events: []control.Event{event("Standup", 3*time.Minute, 18*time.Minute)},
+			paused: now.Add(time.Hour),
+			want: status{
+				Text:       "Standup in 3m",
+				Tooltip:    "Reminders paused until 15:00\n14:03–14:18  Standup",
+				Class:      "paused",
+				Percentage: 95,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newStatus(tt.events, tt.paused, now))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Package conference detects video conferencing links in calendar events.
+package conference
+
+import (
+	"html"
+	"regexp"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+// DefaultIcon is the icon of built-in providers and of providers configured
+// without icon.
+// https://specifications.freedesktop.org/icon-naming-spec/latest/ar01s04.html
+const DefaultIcon = "camera-web"
+
+// Provider matches the meeting links of a video conferencing service.
+type Provider struct {
+	Name    string
+	Icon    string
+	Pattern *regexp.Regexp
+}
+
+// Link is a meeting link found in an event.
+type Link struct {
+	URL      string
+	Provider string
+	Icon     string
+}
+
+// urlChars matches the remainder of a URL embedded in text or HTML.
+const urlChars = `[^\s"'<>()\[\]]*`
+
+// Providers are the built-in providers, tried in order after the configured
+// ones.
+var Providers = []Provider{
+	{
+		Name:    "Google Meet",
+		Icon:    DefaultIcon,
+		Pattern: regexp.MustCompile(`https://meet\.google\.com/[a-z]{3}-[a-z]{4}-[a-z]{3}` + urlChars),
+	},
+	{
+		Name:    "Zoom",
+		Icon:    DefaultIcon,
+		Pattern: regexp.MustCompile(`https://(?:[\w-]+\.)*zoom(?:gov)?\.us/(?:j|my|w|s)/` + urlChars),
+	},
+	{
+		Name: "Microsoft Teams",
+		Icon: DefaultIcon,
+		Pattern: regexp.MustCompile(
+			`https://teams\.(?:microsoft|live)\.com/(?:l/meetup-join|meet)/` + urlChars),
+	},
+	{
+		Name:    "Webex",
+		Icon:    DefaultIcon,
+		Pattern: regexp.MustCompile(`https://[\w-]+\.webex\.com/(?:[\w-]+/)?(?:j\.php|meet|join)` + urlChars),
+	},
+	{
+		Name:    "Jitsi",
+		Icon:    DefaultIcon,
+		Pattern: regexp.MustCompile(`https://meet\.jit\.si/[\w-]+` + urlChars),
+	},
+}
+
+func providers() []Provider {
+	p := make([]Provider, 0, len(config.Cfg.ConferenceProviders)+len(Providers))
+	for _, c := range config.Cfg.ConferenceProviders {
+		icon := c.Icon
+		if icon == "" {
+			icon = DefaultIcon
+		}
+		p = append(p, Provider{Name: c.Name, Icon: icon, Pattern: c.Pattern.Regexp})
+	}
+	return append(p, Providers...)
+}
+
+// Find returns the meeting link of an event. The Google Meet link, the video
+// entry points of the conference data, the location and the description are
+// searched in that order.
+func Find(e *calendar.Event) (Link, bool) {
+	var texts []string
+	if e.HangoutLink != "" {
+		texts = append(texts, e.HangoutLink)
+	}
+	if e.ConferenceData != nil {
+		for _, ep := range e.ConferenceData.EntryPoints {
+			if ep.EntryPointType == "video" {
+				texts = append(texts, ep.Uri)
+			}
+		}
+	}
+	// descriptions are HTML, so links may contain escaped ampersands
+	texts = append(texts, e.Location, html.UnescapeString(e.Description))
+
+	ps := providers()
+	for _, text := range texts {
+		for _, p := range ps {
+			if url := p.Pattern.FindString(text); url != "" {
+				return Link{URL: url, Provider: p.Name, Icon: p.Icon}, true
+			}
+		}
+	}
+
+	// Video entry points of unknown providers, e.g. added by a third-party
+	// add-on, are still meeting links.
+	if e.ConferenceData != nil {
+		for _, ep := range e.ConferenceData.EntryPoints {
+			if ep.EntryPointType == "video" && ep.Uri != "" {
+				l := Link{URL: ep.Uri, Icon: DefaultIcon}
+				if cs := e.ConferenceData.ConferenceSolution; cs != nil {
+					l.Provider = cs.Name
+				}
+				return l, true
+			}
+		}
+	}
+	// Legacy Hangouts and other Meet URLs not matched above are still the
+	// event's meeting link.
+	if e.HangoutLink != "" {
+		return Link{URL: e.HangoutLink, Provider: "Google Meet", Icon: DefaultIcon}, true
+	}
+	return Link{}, false
+}
@@ -0,0 +1,169 @@
+package conference
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *calendar.Event
+		want  Link
+		found bool
+	}{
+		{
+			name:  "no link",
+			event: &calendar.Event{Description: "Lunch at the usual place", Location: "Cafeteria"},
+		},
+		{
+			name:  "hangout link",
+			event: &calendar.Event{HangoutLink: "https://meet.google.com/abc-defg-hij"},
+			want:  Link{URL: "https://meet.google.com/abc-defg-hij", Provider: "Google Meet", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name: "zoom entry point",
+			event: &calendar.Event{ConferenceData: &calendar.ConferenceData{
+				ConferenceSolution: &calendar.ConferenceSolution{Name: "Zoom Meeting"},
+				EntryPoints: []*calendar.EntryPoint{
+					{EntryPointType: "phone", Uri: "tel:+1-646-558-8656,,81234567890#"},
+					{EntryPointType: "video", Uri: "https://us02web.zoom.us/j/81234567890?pwd=aBcDeFgHiJkLmNoPqRsTuVwXyZ.1"},
+				},
+			}},
+			want: Link{
+				URL:      "https://us02web.zoom.us/j/81234567890?pwd=aBcDeFgHiJkLmNoPqRsTuVwXyZ.1",
+				Provider: "Zoom",
+				Icon:     DefaultIcon,
+			},
+			found: true,
+		},
+		{
+			name: "unknown entry point",
+			event: &calendar.Event{ConferenceData: &calendar.ConferenceData{
+				ConferenceSolution: &calendar.ConferenceSolution{Name: "Whereby"},
+				EntryPoints: []*calendar.EntryPoint{
+					{EntryPointType: "video", Uri: "https://whereby.com/team-standup"},
+				},
+			}},
+			want:  Link{URL: "https://whereby.com/team-standup", Provider: "Whereby", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name: "zoom in location",
+			event: &calendar.Event{
+				Location:    "https://acme.zoom.us/j/98765432100",
+				Description: "Agenda: quarterly planning",
+			},
+			want:  Link{URL: "https://acme.zoom.us/j/98765432100", Provider: "Zoom", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name: "zoom invitation text",
+			event: &calendar.Event{Description: "Jane Doe is inviting you to a scheduled Zoom meeting.\n\n" +
+				"Join Zoom Meeting\nhttps://zoom.us/j/5551234567?pwd=S0V2dGxBc1pqZz09\n\n" +
+				"Meeting ID: 555 123 4567\nPasscode: 123456\n"},
+			want:  Link{URL: "https://zoom.us/j/5551234567?pwd=S0V2dGxBc1pqZz09", Provider: "Zoom", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name: "teams html invitation",
+			event: &calendar.Event{Description: `<br>________________________________________________________________________________<br>` +
+				`<b>Microsoft Teams meeting</b><br><b>Join on your computer, mobile app or room device</b><br>` +
+				`<a href="https://teams.microsoft.com/l/meetup-join/19%3ameeting_NzQ5ZjE4ODEtYjE3Mi00%40thread.v2/0?context=%7b%22Tid%22%3a%2272f988bf%22%7d&amp;btype=a&amp;role=a">Click here to join the meeting</a><br>` +
+				`Meeting ID: 123 456 789 012<br>Passcode: aBc123<br>`},
+			want: Link{
+				URL:      "https://teams.microsoft.com/l/meetup-join/19%3ameeting_NzQ5ZjE4ODEtYjE3Mi00%40thread.v2/0?context=%7b%22Tid%22%3a%2272f988bf%22%7d&btype=a&role=a",
+				Provider: "Microsoft Teams",
+				Icon:     DefaultIcon,
+			},
+			found: true,
+		},
+		{
+			name:  "teams live",
+			event: &calendar.Event{Description: "Join: https://teams.live.com/meet/9876543210123?p=AbCdEfGh"},
+			want: Link{
+				URL:      "https://teams.live.com/meet/9876543210123?p=AbCdEfGh",
+				Provider: "Microsoft Teams",
+				Icon:     DefaultIcon,
+			},
+			found: true,
+		},
+		{
+			name: "webex invitation",
+			event: &calendar.Event{Description: "When it's time, join the Webex meeting here.\n\n" +
+				"Join meeting\n<https://acme.webex.com/acme/j.php?MTID=m4f9c0e6e1b2a3d4c5e6f7a8b9c0d1e2f>\n\n" +
+				"More ways to join:\nJoin by meeting number\nMeeting number (access code): 2634 123 4567"},
+			want: Link{
+				URL:      "https://acme.webex.com/acme/j.php?MTID=m4f9c0e6e1b2a3d4c5e6f7a8b9c0d1e2f",
+				Provider: "Webex",
+				Icon:     DefaultIcon,
+			},
+			found: true,
+		},
+		{
+			name:  "webex personal room",
+			event: &calendar.Event{Location: "https://acme.webex.com/meet/jdoe"},
+			want:  Link{URL: "https://acme.webex.com/meet/jdoe", Provider: "Webex", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name:  "jitsi",
+			event: &calendar.Event{Description: `Video call: <a href="https://meet.jit.si/WeeklyRetroAcme">https://meet.jit.si/WeeklyRetroAcme</a>`},
+			want:  Link{URL: "https://meet.jit.si/WeeklyRetroAcme", Provider: "Jitsi", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name: "hangout link preferred over description",
+			event: &calendar.Event{
+				HangoutLink: "https://meet.google.com/xyz-abcd-efg",
+				Description: "Backup: https://zoom.us/j/5551234567",
+			},
+			want:  Link{URL: "https://meet.google.com/xyz-abcd-efg", Provider: "Google Meet", Icon: DefaultIcon},
+			found: true,
+		},
+		{
+			name:  "legacy hangout link",
+			event: &calendar.Event{HangoutLink: "https://hangouts.google.com/hangouts/_/example.com/standup"},
+			want: Link{
+				URL:      "https://hangouts.google.com/hangouts/_/example.com/standup",
+				Provider: "Google Meet",
+				Icon:     DefaultIcon,
+			},
+			found: true,
+		},
+		{
+			name:  "zoom webinar registration is not a meeting link",
+			event: &calendar.Event{Description: "Register at https://zoom.us/webinar/register/WN_abc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := Find(tt.event)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindConfiguredProvider(t *testing.T) {
+	prev := config.Cfg.ConferenceProviders
+	config.Cfg.ConferenceProviders = []config.ConferenceProvider{
+		{Name: "Internal", Pattern: config.Regexp{Regexp: regexp.MustCompile(`https://meet\.acme\.internal/\S+`)}},
+		{Name: "Zoom (Acme)", Icon: "zoom", Pattern: config.Regexp{Regexp: regexp.MustCompile(`https://acme\.zoom\.us/j/\d+`)}},
+	}
+	t.Cleanup(func() { config.Cfg.ConferenceProviders = prev })
+
+	got, found := Find(&calendar.Event{Location: "https://meet.acme.internal/standup"})
+	assert.True(t, found)
+	assert.Equal(t, Link{URL: "https://meet.acme.internal/standup", Provider: "Internal", Icon: DefaultIcon}, got)
+
+	got, found = Find(&calendar.Event{Location: "https://acme.zoom.us/j/98765432100?pwd=x"})
+	assert.True(t, found)
+	assert.Equal(t, Link{URL: "https://acme.zoom.us/j/98765432100", Provider: "Zoom (Acme)", Icon: "zoom"}, got)
+}
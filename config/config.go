@@ -1,10 +1,14 @@
 package config
 
 import (
+	"errors"
 	"io"
 	"log"
 	"os"
 	"path"
+	"regexp"
+	"sort"
+	"text/template"
 	"time"
 
 	"github.com/pelletier/go-toml/v2"
@@ -28,16 +32,49 @@ func init() {
 var Cfg = struct {
 	ClientSecretPath     string
 	TokenPath            string
+	StatePath            string
 	CalendarID           string
+	Calendars            []Calendar
 	PollInterval         Duration
 	LookaheadInterval    Duration
 	LocationPollInterval Duration
+	PageSize             int64
+	Reminders            []Duration
+	MergeReminders       bool
+	AllDayReminders      []Duration
+	SnoozeDurations      []Duration
+	ChangeHorizon        Duration
+	EndReminder          Duration
+	EndNotification      bool
+	OverlapWarning       Duration
+	ReminderStyles       []ReminderStyle
+	Rules                []Rule
+	ConferenceProviders  []ConferenceProvider
+	AllowRSVP            bool
+	RunningLate          RunningLate
 	SlackTokenFile       string
+	SlackBaseURL         string
+	SlackReminders       bool
+	Webhooks             []Webhook
+	Terminal             Terminal
+	Push                 Push
 	Debug                bool
 }{
 	PollInterval:         Duration{3 * time.Minute},
 	LookaheadInterval:    Duration{24 * time.Hour},
 	LocationPollInterval: Duration{15 * time.Minute},
+	SlackBaseURL:         "https://slack.com/api/",
+	AllDayReminders:      []Duration{{7 * time.Hour}},
+	SnoozeDurations:      []Duration{{time.Minute}, {5 * time.Minute}},
+	ChangeHorizon:        Duration{time.Hour},
+	RunningLate: RunningLate{
+		Delay: Duration{5 * time.Minute},
+	},
+	Push: Push{
+		ListenAddress: "localhost:8085",
+		ChannelTTL:    Duration{24 * time.Hour},
+		PollInterval:  Duration{15 * time.Minute},
+	},
 }
 
 func Parse(configFilePath string) {
@@ -56,15 +93,71 @@ func Parse(configFilePath string) {
 	if Cfg.ClientSecretPath == "" {
 		Cfg.ClientSecretPath = path.Join(configDir, "gcal-notify", "client-secret.json")
 	}
-	if Cfg.TokenPath == "" {
+	if Cfg.TokenPath == "" || Cfg.StatePath == "" {
 		cacheDir, err := os.UserCacheDir()
 		if err != nil {
 			log.Fatalf("Failed to get cache directory: %v", err)
 		}
-		Cfg.TokenPath = path.Join(cacheDir, "gcal-notify", "token.json")
+		if Cfg.TokenPath == "" {
+			Cfg.TokenPath = path.Join(cacheDir, "gcal-notify", "token.json")
+		}
+		if Cfg.StatePath == "" {
+			Cfg.StatePath = path.Join(cacheDir, "gcal-notify", "state.json")
+		}
+	}
+	if Cfg.CalendarID != "" {
+		Cfg.Calendars = append([]Calendar{{ID: Cfg.CalendarID}}, Cfg.Calendars...)
+	}
+	for _, c := range Cfg.Calendars {
+		if c.ID == "" {
+			log.Fatal("Calendar without ID configured")
+		}
+	}
+	for _, p := range Cfg.ConferenceProviders {
+		if p.Name == "" || p.Pattern.Regexp == nil {
+			log.Fatal("Conference provider without name or pattern configured")
+		}
+	}
+	for i := range Cfg.Webhooks {
+		w := &Cfg.Webhooks[i]
+		if w.URL == "" {
+			log.Fatal("Webhook without URL configured")
+		}
+		if w.Name == "" {
+			w.Name = w.URL
+		}
+		if w.Method == "" {
+			w.Method = "POST"
+		}
+		if w.Attempts == 0 {
+			w.Attempts = 3
+		}
+	}
+	for _, r := range Cfg.ReminderStyles {
+		if !validUrgency(r.Urgency) {
+			log.Fatalf("Invalid urgency %q of reminder style, expected low, normal or critical", r.Urgency)
+		}
 	}
-	if Cfg.CalendarID == "" {
-		log.Fatal("No calendar ID configured")
+	for _, r := range Cfg.Rules {
+		if !validUrgency(r.Urgency) {
+			log.Fatalf("Invalid urgency %q of rule %q, expected low, normal or critical", r.Urgency, r.Name)
+		}
+		switch r.Transparency {
+		case "", "opaque", "transparent":
+		default:
+			log.Fatalf("Invalid transparency %q of rule %q, expected opaque or transparent", r.Transparency, r.Name)
+		}
+	}
+	sort.SliceStable(Cfg.ReminderStyles, func(i, j int) bool {
+		return Cfg.ReminderStyles[i].Before.D < Cfg.ReminderStyles[j].Before.D
+	})
+	switch Cfg.RunningLate.Via {
+	case "", "slack", "comment":
+	default:
+		log.Fatalf("Invalid running late channel %q, expected slack or comment", Cfg.RunningLate.Via)
+	}
+	if Cfg.RunningLate.Message.Template == nil {
+		Cfg.RunningLate.Message.Template = template.Must(template.New("message").Parse(DefaultRunningLateMessage))
 	}
 	if Cfg.SlackTokenFile == "" {
 		Cfg.SlackTokenFile = path.Join(configDir, "gcal-notify", "slack-token")
@@ -75,9 +168,154 @@ func Parse(configFilePath string) {
 	}
 }
 
+// Calendar describes a watched calendar. Reminders, if set, replace the
+// reminders of timed events configured in Google Calendar or, with
+// MergeReminders, are added to them.
+type Calendar struct {
+	ID             string
+	Label          string
+	Icon           string
+	Reminders      []Duration
+	MergeReminders bool
+}
+
+// Push configures push notifications via Calendar watch channels. They are
+// enabled by setting URL.
+type Push struct {
+	URL           string   // public HTTPS address forwarded to ListenAddress
+	ListenAddress string   // local address of the receiver
+	ChannelTTL    Duration // requested lifetime of a watch channel
+	PollInterval  Duration // poll interval while all channels are active
+}
+
+// ReminderStyle describes the presentation of reminders firing at most
+// Before ahead of the start of an event. If several styles match, the one with
+// the smallest Before applies.
+type ReminderStyle struct {
+	Before        Duration
+	Urgency       string  // "low", "normal" or "critical"
+	ExpireTimeout Timeout // never expires if unset
+	Category      string  // e.g. "x-gnome.calendar"
+	SoundName     string  // freedesktop sound theme name
+	Resident      bool    // keep the notification after an action was invoked
+	Transient     bool    // bypass the notification daemon's persistence
+}
+
+// Rule changes how matching events are notified. A rule matches if all of its
+// set conditions match. All matching rules apply in order, so later rules
+// override the reminders and urgency of earlier ones.
+type Rule struct {
+	Name string
+
+	// conditions
+	Summary      Regexp
+	Organizer    string // e-mail address
+	Calendar     string // calendar ID
+	MinAttendees int
+	MaxAttendees int
+	EventType    string // e.g. "default", "focusTime" or "outOfOffice"
+	ColorID      string
+	Transparency string // "opaque" (busy) or "transparent" (free)
+	Response     string // own response, e.g. "needsAction" or "tentative"
+
+	// actions
+	Skip           bool       // do not notify at all
+	Reminders      []Duration // replace the event's reminders
+	MergeReminders bool       // add Reminders instead of replacing them
+	Urgency        string     // "low", "normal" or "critical"
+	Prefix         string     // prepended to the summary
+}
+
+// DefaultRunningLateMessage is the default message of the running late
+// action.
+const DefaultRunningLateMessage = "Sorry, I'm running about {{.Delay}} late for {{.Summary}}."
+
+// RunningLate configures the running late action of notifications, which
+// tells the organizer of a meeting about a delay. It is enabled by setting Via.
+type RunningLate struct {
+	Via     string   // "slack" for a direct message or "comment" on the event
+	Delay   Duration // announced delay
+	Message Template // rendered with .Summary, .Start and .Delay
+}
+
+// ConferenceProvider describes additional video conferencing links to
+// detect in events. Configured providers take precedence over the built-in
+// ones.
+type ConferenceProvider struct {
+	Name    string
+	Pattern Regexp
+	Icon    string
+}
+
+// Webhook describes an HTTP endpoint to which notifications are delivered,
+// e.g. ntfy, Gotify or Matrix. Body is a text/template rendered with the
+// notification.
+type Webhook struct {
+	Name     string
+	URL      string
+	Method   string
+	Headers  map[string]string
+	Body     string
+	Attempts int  // delivery attempts, with exponential backoff in between
+	DryRun   bool // print the rendered request instead of sending it
+}
+
+// Terminal configures the delivery of notifications as text to a terminal.
+// It is used automatically if the session bus is unavailable.
+type Terminal struct {
+	Enabled bool   // use in addition to desktop notifications
+	Output  string // file to append to, e.g. a TTY, instead of stdout
+	Bell    bool   // ring the terminal bell
+	Escape  string // "osc9" or "osc777" for terminal-native notifications
+}
+
+func validUrgency(u string) bool {
+	switch u {
+	case "", "low", "normal", "critical":
+		return true
+	}
+	return false
+}
+
 type Duration struct{ D time.Duration }
 
 func (d *Duration) UnmarshalText(data []byte) (err error) {
 	d.D, err = time.ParseDuration(string(data))
 	return
 }
+
+// Timeout is how long a notification is shown: a duration, "never" or
+// "default", which leaves it to the notification daemon.
+type Timeout struct {
+	D       time.Duration // zero means never
+	Default bool
+}
+
+func (t *Timeout) UnmarshalText(data []byte) (err error) {
+	*t = Timeout{}
+	switch s := string(data); s {
+	case "never":
+	case "default":
+		t.Default = true
+	default:
+		t.D, err = time.ParseDuration(s)
+		if err == nil && t.D <= 0 {
+			err = errors.New(`timeout must be positive, "never" or "default"`)
+		}
+	}
+	return
+}
+
+type Template struct{ *template.Template }
+
+func (t *Template) UnmarshalText(data []byte) (err error) {
+	t.Template, err = template.New("").Parse(string(data))
+	return
+}
+
+type Regexp struct{ *regexp.Regexp }
+
+func (r *Regexp) UnmarshalText(data []byte) (err error) {
+	r.Regexp, err = regexp.Compile(string(data))
+	return
+}
@@ -0,0 +1,91 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/svenschwermer/gcal-notify/desktop"
+)
+
+// ErrNotRunning is returned by Dial if no daemon is running.
+var ErrNotRunning = errors.New("daemon not running")
+
+// Client calls the control interface of a running daemon.
+type Client struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+}
+
+// Dial connects to the daemon on the session bus.
+func Dial() (*Client, error) {
+	conn, err := desktop.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	var running bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, Name).Store(&running); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to look up %s: %w", Name, err)
+	}
+	if !running {
+		conn.Close()
+		return nil, ErrNotRunning
+	}
+	return &Client{conn: conn, obj: conn.Object(Name, Path)}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) ListUpcoming(ctx context.Context) ([]Event, error) {
+	var list []Event
+	err := c.obj.CallWithContext(ctx, Interface+".ListUpcoming", 0).Store(&list)
+	return list, err
+}
+
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.obj.CallWithContext(ctx, Interface+".Refresh", 0).Err
+}
+
+func (c *Client) SnoozeAll(ctx context.Context, d time.Duration) (int, error) {
+	var n uint32
+	err := c.obj.CallWithContext(ctx, Interface+".SnoozeAll", 0, d.String()).Store(&n)
+	return int(n), err
+}
+
+func (c *Client) Pause(ctx context.Context, d time.Duration) error {
+	return c.obj.CallWithContext(ctx, Interface+".Pause", 0, d.String()).Err
+}
+
+func (c *Client) Resume(ctx context.Context) error {
+	return c.obj.CallWithContext(ctx, Interface+".Resume", 0).Err
+}
+
+// PausedUntil returns the end of the current pause, or the zero time if not
+// paused.
+func (c *Client) PausedUntil(ctx context.Context) (time.Time, error) {
+	var until int64
+	if err := c.obj.CallWithContext(ctx, Interface+".PausedUntil", 0).Store(&until); err != nil {
+		return time.Time{}, err
+	}
+	if until == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(until, 0), nil
+}
+
+// Subscribe delivers the signals about changes of the upcoming events to the
+// returned channel.
+func (c *Client) Subscribe() (<-chan *dbus.Signal, error) {
+	if err := c.conn.AddMatchSignal(dbus.WithMatchObjectPath(Path), dbus.WithMatchInterface(Interface)); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to signals: %w", err)
+	}
+	ch := make(chan *dbus.Signal, 16)
+	c.conn.Signal(ch)
+	return ch, nil
+}
@@ -0,0 +1,186 @@
+// Package control exports a D-Bus service on the session bus to query and
+// control the running daemon, and provides a client for it.
+package control
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+const (
+	Name      = "org.gcalnotify.Daemon"
+	Path      = dbus.ObjectPath("/org/gcalnotify/Daemon")
+	Interface = "org.gcalnotify.Daemon"
+)
+
+// Daemon is controlled through the service. It is implemented by the
+// events.Notifier.
+type Daemon interface {
+	Upcoming() []*events.Event
+	Refresh()
+	SnoozeAll(d time.Duration) int
+	Pause(d time.Duration)
+	Resume()
+	PausedUntil() time.Time
+}
+
+// Event describes an upcoming event. It is transferred as D-Bus struct of
+// signature (sssxxbsss).
+type Event struct {
+	CalendarID    string
+	ID            string
+	Summary       string
+	Start         int64 // Unix time
+	End           int64 // Unix time
+	AllDay        bool
+	ConferenceURL string
+	Link          string
+	Location      string
+}
+
+// NewEvent describes e.
+func NewEvent(e *events.Event) Event {
+	return Event{
+		CalendarID:    e.CalendarID,
+		ID:            e.ID,
+		Summary:       e.Prefix + e.Summary,
+		Start:         e.Start.Unix(),
+		End:           e.End.Unix(),
+		AllDay:        e.AllDay,
+		ConferenceURL: e.Conference.URL,
+		Link:          e.Link,
+		Location:      e.Location,
+	}
+}
+
+var eventArg = []introspect.Arg{{Name: "event", Type: "(sssxxbsss)"}}
+
+// Service exports a Daemon and emits signals about changes of the upcoming
+// events. It implements events.Observer.
+type Service struct {
+	conn *dbus.Conn
+}
+
+// Export exports d on conn and claims the well-known Name. It fails if
+// another daemon is running already.
+func Export(conn *dbus.Conn, d Daemon) (*Service, error) {
+	obj := &object{d: d}
+	if err := conn.Export(obj, Path, Interface); err != nil {
+		return nil, fmt.Errorf("failed to export control interface: %w", err)
+	}
+	node := &introspect.Node{
+		Name: string(Path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    Interface,
+				Methods: introspect.Methods(obj),
+				Signals: []introspect.Signal{
+					{Name: "EventAdded", Args: eventArg},
+					{Name: "EventChanged", Args: eventArg},
+					{Name: "EventRemoved", Args: eventArg},
+				},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), Path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, fmt.Errorf("failed to export introspection: %w", err)
+	}
+
+	reply, err := conn.RequestName(Name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request name %s: %w", Name, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("name %s already taken, is another instance running?", Name)
+	}
+	return &Service{conn: conn}, nil
+}
+
+func (s *Service) EventAdded(e *events.Event) {
+	s.emit("EventAdded", e)
+}
+
+func (s *Service) EventChanged(e *events.Event) {
+	s.emit("EventChanged", e)
+}
+
+func (s *Service) EventRemoved(e *events.Event) {
+	s.emit("EventRemoved", e)
+}
+
+func (s *Service) emit(signal string, e *events.Event) {
+	if err := s.conn.Emit(Path, Interface+"."+signal, NewEvent(e)); err != nil {
+		log.Printf("Failed to emit %s signal: %v", signal, err)
+	}
+}
+
+// object implements the methods of the D-Bus interface.
+type object struct {
+	d Daemon
+}
+
+// ListUpcoming returns the events that have not ended yet, ordered by start.
+func (o *object) ListUpcoming() ([]Event, *dbus.Error) {
+	upcoming := o.d.Upcoming()
+	list := make([]Event, len(upcoming))
+	for i, e := range upcoming {
+		list[i] = NewEvent(e)
+	}
+	return list, nil
+}
+
+// Refresh polls the calendars right away.
+func (o *object) Refresh() *dbus.Error {
+	o.d.Refresh()
+	return nil
+}
+
+// SnoozeAll snoozes all shown reminders by a duration like "5m" and returns
+// their number.
+func (o *object) SnoozeAll(duration string) (uint32, *dbus.Error) {
+	d, err := parseDuration(duration)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(o.d.SnoozeAll(d)), nil
+}
+
+// Pause holds back reminders for a duration like "1h".
+func (o *object) Pause(duration string) *dbus.Error {
+	d, err := parseDuration(duration)
+	if err != nil {
+		return err
+	}
+	o.d.Pause(d)
+	return nil
+}
+
+// Resume ends a pause.
+func (o *object) Resume() *dbus.Error {
+	o.d.Resume()
+	return nil
+}
+
+// PausedUntil returns the end of the current pause as Unix time, or 0 if not
+// paused.
+func (o *object) PausedUntil() (int64, *dbus.Error) {
+	until := o.d.PausedUntil()
+	if until.IsZero() {
+		return 0, nil
+	}
+	return until.Unix(), nil
+}
+
+func parseDuration(s string) (time.Duration, *dbus.Error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, dbus.NewError(Interface+".Error.InvalidDuration", []any{fmt.Sprintf("invalid duration %q", s)})
+	}
+	return d, nil
+}
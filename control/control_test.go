@@ -0,0 +1,82 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/conference"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+// fakeDaemon records the calls made through the service.
+type fakeDaemon struct {
+	upcoming  []*events.Event
+	refreshed bool
+	snoozed   time.Duration
+	paused    time.Duration
+	resumed   bool
+	until     time.Time
+}
+
+func (d *fakeDaemon) Upcoming() []*events.Event { return d.upcoming }
+func (d *fakeDaemon) Refresh()                  { d.refreshed = true }
+func (d *fakeDaemon) Pause(p time.Duration)     { d.paused = p }
+func (d *fakeDaemon) Resume()                   { d.resumed = true }
+func (d *fakeDaemon) PausedUntil() time.Time    { return d.until }
+
+func (d *fakeDaemon) SnoozeAll(s time.Duration) int {
+	d.snoozed = s
+	return 2
+}
+
+func TestObject(t *testing.T) {
+	start := time.Date(2030, 5, 12, 14, 0, 0, 0, time.UTC)
+	d := &fakeDaemon{upcoming: []*events.Event{{
+		CalendarID: "me@example.com",
+		ID:         "abc",
+		Summary:    "Planning",
+		Prefix:     "(optional) ",
+		Start:      start,
+		End:        start.Add(time.Hour),
+		Conference: conference.Link{URL: "https://meet.google.com/abc-defg-hij"},
+	}}}
+	o := &object{d: d}
+
+	list, err := o.ListUpcoming()
+	require.Nil(t, err)
+	assert.Equal(t, []Event{{
+		CalendarID:    "me@example.com",
+		ID:            "abc",
+		Summary:       "(optional) Planning",
+		Start:         start.Unix(),
+		End:           start.Add(time.Hour).Unix(),
+		ConferenceURL: "https://meet.google.com/abc-defg-hij",
+	}}, list)
+
+	require.Nil(t, o.Refresh())
+	assert.True(t, d.refreshed)
+
+	n, err := o.SnoozeAll("5m")
+	require.Nil(t, err)
+	assert.Equal(t, uint32(2), n)
+	assert.Equal(t, 5*time.Minute, d.snoozed)
+
+	require.Nil(t, o.Pause("1h30m"))
+	assert.Equal(t, 90*time.Minute, d.paused)
+	err = o.Pause("soon")
+	require.NotNil(t, err)
+	assert.Equal(t, "org.gcalnotify.Daemon.Error.InvalidDuration", err.Name)
+
+	require.Nil(t, o.Resume())
+	assert.True(t, d.resumed)
+
+	until, err := o.PausedUntil()
+	require.Nil(t, err)
+	assert.Zero(t, until)
+	d.until = start
+	until, err = o.PausedUntil()
+	require.Nil(t, err)
+	assert.Equal(t, start.Unix(), until)
+}
@@ -0,0 +1,163 @@
+// Package desktop delivers notifications via the freedesktop notification
+// D-Bus API.
+package desktop
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/esiqveland/notify"
+	"github.com/godbus/dbus/v5"
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+// SessionBus opens a private connection to the session bus.
+func SessionBus() (*dbus.Conn, error) {
+	sessionBus, err := dbus.SessionBusPrivate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connection to session dbus: %w", err)
+	}
+	if err := sessionBus.Auth(nil); err != nil {
+		sessionBus.Close()
+		return nil, fmt.Errorf("failed to authenticate against session dbus: %w", err)
+	}
+	if err := sessionBus.Hello(); err != nil {
+		sessionBus.Close()
+		return nil, fmt.Errorf("failed to send hello message to session dbus: %w", err)
+	}
+	return sessionBus, nil
+}
+
+type Sink struct {
+	notifier notify.Notifier
+	handler  events.Handler
+
+	ids    map[uint32]uint32 // key: notification ID, value: D-Bus ID
+	dbusID map[uint32]uint32 // key: D-Bus ID, value: notification ID
+	mtx    sync.Mutex
+}
+
+func New(conn *dbus.Conn, handler events.Handler) (*Sink, error) {
+	s := &Sink{
+		handler: handler,
+		ids:     make(map[uint32]uint32),
+		dbusID:  make(map[uint32]uint32),
+	}
+	var err error
+	s.notifier, err = notify.New(conn, notify.WithOnAction(s.onAction), notify.WithOnClosed(s.onClosed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifier: %w", err)
+	}
+	// fails unless a notification daemon is running or can be activated
+	info, err := s.notifier.GetServerInformation()
+	if err != nil {
+		s.notifier.Close()
+		return nil, fmt.Errorf("no notification daemon: %w", err)
+	}
+	config.Debug.Printf("desktop: notification daemon %s %s", info.Name, info.Version)
+	return s, nil
+}
+
+func (s *Sink) Send(n *events.Notification) error {
+	return s.send(n, 0)
+}
+
+// Update replaces a notification that is still shown. Closed notifications are
+// not brought back.
+func (s *Sink) Update(n *events.Notification) error {
+	s.mtx.Lock()
+	replaces, ok := s.ids[n.ID]
+	s.mtx.Unlock()
+	if !ok {
+		return nil
+	}
+	return s.send(n, replaces)
+}
+
+func (s *Sink) send(n *events.Notification, replaces uint32) error {
+	not := notify.Notification{
+		AppName:       "gcal-notify",
+		ReplacesID:    replaces,
+		AppIcon:       n.Icon,
+		Summary:       n.Summary,
+		Body:          n.Body,
+		Hints:         map[string]dbus.Variant{},
+		ExpireTimeout: notify.ExpireTimeoutNever,
+	}
+	for _, a := range n.Actions {
+		not.Actions = append(not.Actions, notify.Action{Key: a.Key, Label: a.Label})
+	}
+	switch n.Urgency {
+	case events.UrgencyLow:
+		not.Hints["urgency"] = dbus.MakeVariant(byte(0))
+	case events.UrgencyCritical:
+		not.Hints["urgency"] = dbus.MakeVariant(byte(2))
+	}
+	if n.ServerTimeout {
+		not.ExpireTimeout = notify.ExpireTimeoutSetByNotificationServer
+	} else {
+		not.ExpireTimeout = n.ExpireTimeout
+	}
+	if n.Category != "" {
+		not.Hints["category"] = dbus.MakeVariant(n.Category)
+	}
+	if n.SoundName != "" {
+		not.Hints["sound-name"] = dbus.MakeVariant(n.SoundName)
+	}
+	if n.Resident {
+		not.Hints["resident"] = dbus.MakeVariant(true)
+	}
+	if n.Transient {
+		not.Hints["transient"] = dbus.MakeVariant(true)
+	}
+
+	id, err := s.notifier.SendNotification(not)
+	if err != nil {
+		return fmt.Errorf("failed to send notification via dbus: %w", err)
+	}
+	config.Debug.Printf("desktop: notification %d has D-Bus ID %d", n.ID, id)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if replaces != 0 && replaces != id {
+		delete(s.dbusID, replaces)
+	}
+	s.ids[n.ID] = id
+	s.dbusID[id] = n.ID
+	return nil
+}
+
+func (s *Sink) Close(id uint32) error {
+	s.mtx.Lock()
+	dbusID, ok := s.ids[id]
+	s.mtx.Unlock()
+	if !ok {
+		return nil
+	}
+	_, err := s.notifier.CloseNotification(dbusID)
+	return err
+}
+
+func (s *Sink) onAction(action *notify.ActionInvokedSignal) {
+	s.mtx.Lock()
+	id, ok := s.dbusID[action.ID]
+	s.mtx.Unlock()
+	if ok {
+		s.handler.OnAction(id, action.ActionKey)
+	}
+}
+
+func (s *Sink) onClosed(closer *notify.NotificationClosedSignal) {
+	config.Debug.Printf("desktop: D-Bus notification closed: reason=%v id=%d", closer.Reason, closer.ID)
+	s.mtx.Lock()
+	id, ok := s.dbusID[closer.ID]
+	if ok {
+		delete(s.dbusID, closer.ID)
+		delete(s.ids, id)
+	}
+	s.mtx.Unlock()
+	if ok {
+		s.handler.OnClosed(id)
+	}
+}
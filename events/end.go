@@ -0,0 +1,78 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+)
+
+// checkEnd delivers the EndReminder of e, if due. Events shorter than the
+// EndReminder do not get one.
+func (n *Notifier) checkEnd(e *Event) {
+	d := config.Cfg.EndReminder.D
+	if d <= 0 || e.AllDay || e.EndReminderID != 0 || e.End.Sub(e.Start) <= d {
+		return
+	}
+	if time.Until(e.End) > d {
+		return
+	}
+	e.EndReminderID = n.notice(e, formatDuration(d)+" left", n.nextEvent(e), "appointment-soon")
+	n.dirty = true
+}
+
+// ended closes the EndReminder of e and, if enabled, delivers the
+// EndNotification.
+func (n *Notifier) ended(e *Event) {
+	if e.EndReminderID != 0 {
+		n.close(e.EndReminderID)
+	}
+	if config.Cfg.EndNotification && !e.AllDay {
+		n.notice(e, "Ended", n.nextEvent(e), "appointment-soon")
+	}
+}
+
+// checkOverlap warns about e starting within OverlapWarning while another
+// event is still running.
+func (n *Notifier) checkOverlap(e *Event) {
+	d := config.Cfg.OverlapWarning.D
+	if d <= 0 || e.AllDay || e.OverlapNotified {
+		return
+	}
+	until := time.Until(e.Start)
+	if until > d || until < 0 {
+		return
+	}
+	for _, o := range n.ev {
+		if o != e && !o.AllDay && o.Start.Before(e.Start) && o.End.After(e.Start) {
+			n.notice(e, "Overlap",
+				fmt.Sprintf("Starts %s, before %s ends at %s", e.Start.Format("15:04"), o.Summary, o.End.Format("15:04")),
+				"dialog-warning")
+			e.OverlapNotified = true
+			n.dirty = true
+			return
+		}
+	}
+}
+
+// nextEvent describes the next timed event after e on the same day, if any.
+func (n *Notifier) nextEvent(e *Event) string {
+	now := time.Now()
+	var next *Event
+	for _, o := range n.ev {
+		if o == e || o.AllDay || !o.Start.After(now) || !SameDay(o.Start, now) {
+			continue
+		}
+		if next == nil || o.Start.Before(next.Start) {
+			next = o
+		}
+	}
+	if next == nil {
+		return ""
+	}
+	in := next.Start.Sub(now).Round(time.Minute)
+	if in < time.Minute {
+		return fmt.Sprintf("Next: %s now", next.Summary)
+	}
+	return fmt.Sprintf("Next: %s in %s (%s)", next.Summary, formatDuration(in), next.Start.Format("15:04"))
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+)
+
+func TestEndAndOverlap(t *testing.T) {
+	prevEnd, prevEnded, prevOverlap := config.Cfg.EndReminder, config.Cfg.EndNotification, config.Cfg.OverlapWarning
+	config.Cfg.EndReminder = config.Duration{D: 5 * time.Minute}
+	config.Cfg.EndNotification = true
+	config.Cfg.OverlapWarning = config.Duration{D: time.Minute}
+	t.Cleanup(func() {
+		config.Cfg.EndReminder, config.Cfg.EndNotification, config.Cfg.OverlapWarning = prevEnd, prevEnded, prevOverlap
+	})
+
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	sink := &memSink{}
+	n.AddSink(sink)
+
+	now := time.Now()
+	review := &Event{CalendarID: "me@example.com", ID: "review", Summary: "Review",
+		Start: now.Add(-25 * time.Minute), End: now.Add(4 * time.Minute)}
+	planning := &Event{CalendarID: "me@example.com", ID: "planning", Summary: "Planning",
+		Start: now.Add(20 * time.Second), End: now.Add(time.Hour)}
+	n.ev["me@example.com/review"] = review
+	n.ev["me@example.com/planning"] = planning
+
+	n.checkReminders()
+	require.Len(t, sink.sent, 2)
+	var endReminder, overlap *Notification
+	for _, not := range sink.sent {
+		if not.Event == review {
+			endReminder = not
+		} else {
+			overlap = not
+		}
+	}
+	require.NotNil(t, endReminder)
+	assert.Equal(t, "5 min left: Review", endReminder.Summary)
+	assert.Equal(t, "Next: Planning now", endReminder.Body)
+	require.NotNil(t, overlap)
+	assert.Equal(t, "Overlap: Planning", overlap.Summary)
+	assert.Contains(t, overlap.Body, "before Review ends at")
+
+	// each fires only once
+	n.checkReminders()
+	assert.Len(t, sink.sent, 2)
+
+	review.End = now.Add(-time.Second)
+	n.checkReminders()
+	require.Len(t, sink.sent, 3)
+	assert.Equal(t, "Ended: Review", sink.sent[2].Summary)
+	assert.Equal(t, []uint32{review.EndReminderID}, sink.closed)
+	assert.NotContains(t, n.ev, "me@example.com/review")
+}
@@ -4,163 +4,522 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/esiqveland/notify"
-	"github.com/godbus/dbus/v5"
 	"github.com/google/go-cmp/cmp"
-	"github.com/svenschwermer/gcal-notify/browser"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/svenschwermer/gcal-notify/conference"
 	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/rules"
 	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/googleapi"
 )
 
 type Notifier struct {
-	svc      *calendar.Service
-	notifier notify.Notifier
-	calID    string
+	svc   *calendar.Service
+	cals  []config.Calendar
+	sinks []Sink
 
-	ev    map[string]*Event // key: ID
+	ev    map[string]*Event // key: calendar ID + "/" + event ID
 	evMtx sync.Mutex
+	dirty bool // ev changed since the state was last saved
 
 	active    map[uint32]*Event // key: notification ID
 	activeMtx sync.Mutex
+	lastID    uint32
 
 	checkNotifications chan struct{}
+	refresh            chan struct{}
+	pushActive         atomic.Bool
+	rsvp               bool // offer RSVP actions
+	messenger          Messenger
+	observers          []Observer
+	pausedUntil        time.Time // guarded by evMtx
 }
 
-func NewNotifier(svc *calendar.Service, calendarID string) (*Notifier, error) {
+func NewNotifier(svc *calendar.Service, calendars []config.Calendar) *Notifier {
 	n := &Notifier{
 		svc:                svc,
-		calID:              calendarID,
+		cals:               calendars,
 		ev:                 make(map[string]*Event),
 		active:             make(map[uint32]*Event),
 		checkNotifications: make(chan struct{}, 1),
+		refresh:            make(chan struct{}, 1),
 	}
-	sessionBus, err := dbus.SessionBusPrivate()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connection to session dbus: %w", err)
-	}
-	if err := sessionBus.Auth(nil); err != nil {
-		return nil, fmt.Errorf("failed to authenticate against session dbus: %w", err)
-	}
-	if err := sessionBus.Hello(); err != nil {
-		return nil, fmt.Errorf("failed to send hello message to session dbus: %w", err)
-	}
-	n.notifier, err = notify.New(sessionBus, notify.WithOnAction(n.onAction), notify.WithOnClosed(n.onClosed))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create notifier: %w", err)
+	if err := n.loadState(); err != nil {
+		log.Printf("Failed to restore state, starting afresh: %v", err)
 	}
-	return n, nil
+	return n
 }
 
+// AddSink registers a backend through which notifications are delivered. It
+// must not be called once polling has started.
+func (n *Notifier) AddSink(s Sink) {
+	n.sinks = append(n.sinks, s)
+}
+
+// Poll keeps the events in sync with the calendars and delivers their
+// reminders. Reminders are only checked after the first sync, so that the
+// restored state does not fire reminders of events changed in the meantime.
 func (n *Notifier) Poll(ctx context.Context) error {
+	syncs := make([]*calendarSync, len(n.cals))
+	for i := range n.cals {
+		syncs[i] = &calendarSync{cal: &n.cals[i]}
+	}
+	n.syncAll(ctx, syncs)
 	go n.notifyWorker(ctx)
-	ticker := time.NewTimer(0)
 	for {
+		interval := config.Cfg.PollInterval.D
+		if n.pushActive.Load() {
+			interval = config.Cfg.Push.PollInterval.D
+		}
+		ticker := time.NewTimer(interval)
 		select {
 		case <-ticker.C:
-			ticker = time.NewTimer(config.Cfg.PollInterval.D)
+		case <-n.refresh:
+			ticker.Stop()
 		case <-ctx.Done():
+			ticker.Stop()
 			return ctx.Err()
 		}
+		n.syncAll(ctx, syncs)
+	}
+}
 
-		timeMin := time.Now()
-		timeMax := timeMin.Add(config.Cfg.LookaheadInterval.D)
-		events, err := n.svc.Events.List(n.calID).Context(ctx).Do(
-			googleapi.QueryParameter("timeMin", timeMin.Format(time.RFC3339)),
-			googleapi.QueryParameter("timeMax", timeMax.Format(time.RFC3339)),
-			googleapi.QueryParameter("singleEvents", "True"),
-		)
-		if err != nil {
-			log.Printf("Failed to query event list: %v", err)
-			continue
+func (n *Notifier) syncAll(ctx context.Context, syncs []*calendarSync) {
+	for _, s := range syncs {
+		if err := n.sync(ctx, s); err != nil {
+			log.Printf("Failed to query event list of %s: %v", s.cal.ID, err)
 		}
+	}
+}
 
-		getReminders := func(er *calendar.EventReminders) []*Reminder {
-			or := er.Overrides
-			if er.UseDefault {
-				or = events.DefaultReminders
-			}
-			r := make([]*Reminder, len(or))
-			for i := range or {
-				r[i] = &Reminder{Before: time.Duration(or[i].Minutes) * time.Minute}
-			}
-			return r
+// Refresh triggers an immediate sync of all calendars.
+func (n *Notifier) Refresh() {
+	select {
+	case n.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// AddObserver registers o to be informed about changes of the upcoming events.
+// It must not be called once polling has started.
+func (n *Notifier) AddObserver(o Observer) {
+	n.observers = append(n.observers, o)
+}
+
+// Upcoming returns copies of the events that have not ended yet, ordered by
+// their start.
+func (n *Notifier) Upcoming() []*Event {
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+	now := time.Now()
+	upcoming := make([]*Event, 0, len(n.ev))
+	for _, e := range n.ev {
+		if e.End.After(now) {
+			c := *e
+			upcoming = append(upcoming, &c)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool {
+		if !upcoming[i].Start.Equal(upcoming[j].Start) {
+			return upcoming[i].Start.Before(upcoming[j].Start)
 		}
+		return upcoming[i].Summary < upcoming[j].Summary
+	})
+	return upcoming
+}
+
+// Pause holds back reminders for d. Reminders that became due in the meantime
+// are delivered afterwards, unless their event has ended.
+func (n *Notifier) Pause(d time.Duration) {
+	n.evMtx.Lock()
+	n.pausedUntil = time.Now().Add(d)
+	n.evMtx.Unlock()
+	config.Debug.Printf("Paused for %v", d)
+}
+
+// Resume ends a pause.
+func (n *Notifier) Resume() {
+	n.evMtx.Lock()
+	n.pausedUntil = time.Time{}
+	n.evMtx.Unlock()
+	config.Debug.Printf("Resumed")
+
+	select {
+	case n.checkNotifications <- struct{}{}:
+	default:
+	}
+}
+
+// PausedUntil returns the end of the current pause, or the zero time if not
+// paused.
+func (n *Notifier) PausedUntil() time.Time {
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+	if time.Now().After(n.pausedUntil) {
+		return time.Time{}
+	}
+	return n.pausedUntil
+}
 
-		n.evMtx.Lock()
+// SetPushActive selects the poll interval used while changes are pushed via
+// watch channels.
+func (n *Notifier) SetPushActive(active bool) {
+	n.pushActive.Store(active)
+}
 
-		deletedEvents := make(map[string]bool, len(n.ev))
-		for id := range n.ev {
+// update merges the events listed for the given calendar into n.ev. If full is
+// set, events is the complete list and cached events missing from it are
+// considered deleted. Otherwise, events are the changes reported by an
+// incremental sync, which are not limited to a time window; events which have
+// ended or start after until, the end of the window of the last full sync, are
+// dropped.
+func (n *Notifier) update(cal *config.Calendar, events *calendar.Events, full bool, until time.Time) {
+	loc := location(cal, events)
+
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+
+	now := time.Now()
+	deletedEvents := make(map[string]bool, len(n.ev))
+	for id, e := range n.ev {
+		if full && e.CalendarID == cal.ID {
 			deletedEvents[id] = true
 		}
+	}
 
-		for _, event := range events.Items {
-			id := event.Id
-			existingEvent, isExisting := n.ev[id]
-			deletedEvents[id] = false
-
-			if event.Status == "cancelled" {
-				if isExisting {
-					config.Debug.Printf("Event %q cancelled", event.Summary)
-					n.closeNotifications(existingEvent)
-					delete(n.ev, id)
+	for _, event := range events.Items {
+		id := eventKey(cal.ID, event.Id)
+		existingEvent, isExisting := n.ev[id]
+		deletedEvents[id] = false
+
+		if event.Status == "cancelled" {
+			if isExisting {
+				config.Debug.Printf("Event %q cancelled", existingEvent.Summary)
+				n.removeEvent(id, existingEvent)
+				if imminent(existingEvent) {
+					n.notifyChange(existingEvent, "Cancelled", "")
 				}
-				continue
 			}
-			if !attending(event) {
-				if isExisting {
-					config.Debug.Printf("Not attending event %q", event.Summary)
-					n.closeNotifications(existingEvent)
-					delete(n.ev, id)
+			continue
+		}
+		if !attending(event) {
+			if isExisting {
+				config.Debug.Printf("Not attending event %q", event.Summary)
+				n.removeEvent(id, existingEvent)
+				if imminent(existingEvent) {
+					n.notifyChange(existingEvent, "Declined", "")
 				}
-				continue
 			}
+			continue
+		}
+
+		e, res, err := newEvent(cal, events, event, loc)
+		if err != nil {
+			log.Printf("Failed to parse event %q: %v", event.Summary, err)
+			continue
+		}
+		if res.Skip {
+			if isExisting {
+				config.Debug.Printf("Skipping event %q according to rules %v", event.Summary, res.Matched)
+				n.removeEvent(id, existingEvent)
+			}
+			continue
+		}
+		if !full && (!e.End.After(now) || e.Start.After(until)) {
+			if isExisting {
+				config.Debug.Printf("Event %q moved out of the time window", e.Summary)
+				n.removeEvent(id, existingEvent)
+			}
+			continue
+		}
 
-			e := &Event{
-				Summary:     event.Summary,
-				Description: event.Description,
-				Hangout:     event.HangoutLink,
-				Link:        event.HtmlLink,
-				Location:    event.Location,
-				Reminders:   getReminders(event.Reminders),
+		if !isExisting {
+			config.Debug.Printf("New event: calendar=%s summary=%q start=%v end=%v reminders=%v",
+				cal.ID, e.Summary, e.Start, e.End, e.Reminders)
+			n.ev[id] = e
+			n.dirty = true
+			for _, o := range n.observers {
+				o.EventAdded(e)
 			}
-			e.Start, err = time.Parse(time.RFC3339, event.Start.DateTime)
-			if err != nil {
-				log.Printf("Failed to parse Start %+v: %v", event.Start, err)
-				continue
+		} else if !cmp.Equal(existingEvent, e, eventCompareOption) {
+			config.Debug.Printf("Changed event: summary=%q diff:\n%s",
+				e.Summary, cmp.Diff(existingEvent, e, eventCompareOption))
+			// a notification updated in place already tells about the move
+			if !n.rearm(existingEvent, e) && !e.Start.Equal(existingEvent.Start) && imminent(existingEvent) {
+				n.notifyChange(e, "Moved", existingEvent.Start.Format("15:04")+" → "+startTime(e, existingEvent.Start))
 			}
-			e.End, err = time.Parse(time.RFC3339, event.End.DateTime)
-			if err != nil {
-				log.Printf("Failed to parse End %+v: %v", event.End, err)
-				continue
+			n.ev[id] = e
+			n.dirty = true
+			for _, o := range n.observers {
+				o.EventChanged(e)
 			}
+		}
+	}
+
+	for id, deleted := range deletedEvents {
+		if deleted {
+			e := n.ev[id]
+			config.Debug.Printf("Event %q deleted", e.Summary)
+			n.removeEvent(id, e)
+			if imminent(e) {
+				n.notifyChange(e, "Cancelled", "")
+			}
+		}
+	}
+	n.saveState()
+}
+
+// removeEvent withdraws the notifications of e and drops it.
+func (n *Notifier) removeEvent(id string, e *Event) {
+	n.closeNotifications(e)
+	delete(n.ev, id)
+	n.dirty = true
+	for _, o := range n.observers {
+		o.EventRemoved(e)
+	}
+}
+
+// location returns the time zone of the listed events of cal.
+func location(cal *config.Calendar, events *calendar.Events) *time.Location {
+	loc, err := time.LoadLocation(events.TimeZone)
+	if err != nil {
+		log.Printf("Failed to load time zone %q of %s: %v", events.TimeZone, cal.ID, err)
+		return time.Local
+	}
+	return loc
+}
+
+// newEvent converts an event listed for cal, whose time zone is loc, and
+// applies the rules to it. The event is nil if the rules skip it.
+func newEvent(cal *config.Calendar, events *calendar.Events, event *calendar.Event, loc *time.Location) (*Event, rules.Result, error) {
+	res := rules.Evaluate(config.Cfg.Rules, cal.ID, event)
+	if res.Skip {
+		return nil, res, nil
+	}
+	e := &Event{
+		CalendarID:  cal.ID,
+		ID:          event.Id,
+		Summary:     event.Summary,
+		Description: event.Description,
+		Link:        event.HtmlLink,
+		Location:    event.Location,
+		AllDay:      event.Start.DateTime == "",
+		Response:    rules.Response(event),
+		Organizer:   organizer(event),
+		Prefix:      res.Prefix,
+		Urgency:     res.Urgency,
+	}
+	e.Reminders = reminders(cal, events, event.Reminders, e.AllDay, res)
+	e.Conference, _ = conference.Find(event)
+	var err error
+	e.Start, err = parseTime(event.Start, loc)
+	if err != nil {
+		return nil, res, fmt.Errorf("failed to parse start %+v: %w", event.Start, err)
+	}
+	e.End, err = parseTime(event.End, loc)
+	if err != nil {
+		return nil, res, fmt.Errorf("failed to parse end %+v: %w", event.End, err)
+	}
+	return e, res, nil
+}
 
-			if !isExisting {
-				config.Debug.Printf("New event: summary=%q start=%v end=%v reminders=%v",
-					e.Summary, e.Start, e.End, e.Reminders)
-				n.ev[id] = e
-			} else if !cmp.Equal(existingEvent, e, eventCompareOption) {
-				config.Debug.Printf("Changed event: summary=%q diff:\n%s",
-					e.Summary, cmp.Diff(existingEvent, e, eventCompareOption))
-				n.closeNotifications(existingEvent)
-				n.ev[id] = e
+// reminders returns the reminders of an event listed for cal, including the
+// ones of the rules matching it. For all-day events, reminders are relative to
+// the start of the day, e.g. 7h means the day before at 17:00. The calendar's
+// default reminders and the locally configured ones, globally, per calendar or
+// by rules, only apply to timed events.
+func reminders(cal *config.Calendar, events *calendar.Events, er *calendar.EventReminders, allDay bool, res rules.Result) []*Reminder {
+	if er == nil {
+		er = &calendar.EventReminders{}
+	}
+	if er.UseDefault && allDay {
+		return withReminders(nil, fromConfig(config.Cfg.AllDayReminders), false)
+	}
+	or := er.Overrides
+	if er.UseDefault {
+		or = events.DefaultReminders
+	}
+	r := make([]*Reminder, len(or))
+	for i := range or {
+		r[i] = &Reminder{Before: time.Duration(or[i].Minutes) * time.Minute}
+	}
+	if allDay {
+		return withReminders(r, nil, true)
+	}
+	r = withReminders(r, fromConfig(config.Cfg.Reminders), config.Cfg.MergeReminders)
+	r = withReminders(r, fromConfig(cal.Reminders), cal.MergeReminders)
+	return withReminders(r, res.Reminders, !res.ReplaceReminders)
+}
+
+// fromConfig converts configured reminder offsets, returning nil if there are
+// none.
+func fromConfig(d []config.Duration) []time.Duration {
+	if len(d) == 0 {
+		return nil
+	}
+	r := make([]time.Duration, len(d))
+	for i := range d {
+		r[i] = d[i].D
+	}
+	return r
+}
+
+// withReminders replaces rs by the locally configured reminders local unless
+// they are nil, or adds them if merge is set. Reminders with the same offset
+// are dropped.
+func withReminders(rs []*Reminder, local []time.Duration, merge bool) []*Reminder {
+	if local != nil && !merge {
+		rs = nil
+	}
+	for _, d := range local {
+		rs = append(rs, &Reminder{Before: d})
+	}
+	seen := make(map[time.Duration]bool, len(rs))
+	unique := rs[:0]
+	for _, r := range rs {
+		if !seen[r.Before] {
+			seen[r.Before] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// parseTime parses the start or end of an event. All-day events only carry a
+// date, which is interpreted as the start of that day in the event's or
+// otherwise the calendar's time zone.
+func parseTime(t *calendar.EventDateTime, loc *time.Location) (time.Time, error) {
+	if t.DateTime != "" {
+		return time.Parse(time.RFC3339, t.DateTime)
+	}
+	if t.TimeZone != "" {
+		if l, err := time.LoadLocation(t.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(time.DateOnly, t.Date, loc)
+}
+
+func eventKey(calendarID, eventID string) string {
+	return calendarID + "/" + eventID
+}
+
+func (n *Notifier) calendar(id string) *config.Calendar {
+	for i := range n.cals {
+		if n.cals[i].ID == id {
+			return &n.cals[i]
+		}
+	}
+	return &config.Calendar{ID: id}
+}
+
+// rearm carries the delivery state of old's reminders over to its changed
+// version e. Only reminders whose due time has changed fire again; if they are
+// due already, they take over a notification of old. The notifications of old
+// that are still shown are updated in place rather than closed. It returns
+// whether there were any.
+func (n *Notifier) rearm(old, e *Event) bool {
+	shift := e.Start.Sub(old.Start)
+	var shown []*Reminder // reminders of old with notifications
+	for _, r := range old.Reminders {
+		if r.Notified {
+			shown = append(shown, r)
+		} else if r.Extra {
+			// keep snoozes at the same time
+			e.Reminders = append(e.Reminders, &Reminder{Before: r.Before + shift, Extra: true})
+		}
+	}
+
+	take := func(match func(*Reminder) bool) *Reminder {
+		for i, r := range shown {
+			if match(r) {
+				shown = append(shown[:i], shown[i+1:]...)
+				return r
 			}
 		}
+		return nil
+	}
+	for _, r := range e.Reminders {
+		due := e.Start.Add(-r.Before)
+		sr := take(func(sr *Reminder) bool { return !sr.Extra && old.Start.Add(-sr.Before).Equal(due) })
+		if sr == nil && !r.Extra && time.Until(due) <= 0 {
+			sr = take(func(*Reminder) bool { return true })
+		}
+		if sr != nil {
+			r.Notified, r.NotificationID = true, sr.NotificationID
+		}
+	}
+	// keep track of the remaining notifications in order to close them later
+	for _, sr := range shown {
+		e.Reminders = append(e.Reminders, &Reminder{
+			Before:         sr.Before + shift,
+			Notified:       true,
+			NotificationID: sr.NotificationID,
+			Extra:          true,
+		})
+	}
+
+	// the end reminder and overlap warning only fire again if their time
+	// has changed
+	if e.End.Equal(old.End) {
+		e.EndReminderID = old.EndReminderID
+	} else if old.EndReminderID != 0 {
+		n.close(old.EndReminderID)
+	}
+	if e.Start.Equal(old.Start) {
+		e.OverlapNotified = old.OverlapNotified
+	}
 
-		for id, deleted := range deletedEvents {
-			if deleted {
-				e := n.ev[id]
-				config.Debug.Printf("Event %q deleted", e.Summary)
-				n.closeNotifications(e)
-				delete(n.ev, id)
+	moved := !e.Start.Equal(old.Start)
+	replaced := false
+	for _, r := range e.Reminders {
+		if r.Notified {
+			not := n.reminderNotification(e, r, moved)
+			not.ID = r.NotificationID
+			if n.replace(not) {
+				replaced = true
 			}
 		}
+	}
+
+	select {
+	case n.checkNotifications <- struct{}{}:
+	default:
+	}
+	return replaced
+}
+
+// imminent returns whether e starts within the ChangeHorizon.
+func imminent(e *Event) bool {
+	until := time.Until(e.Start)
+	return until > 0 && until <= config.Cfg.ChangeHorizon.D
+}
+
+// startTime formats the start of e, including the date unless it is on the
+// same day as ref.
+func startTime(e *Event, ref time.Time) string {
+	if SameDay(e.Start, ref) {
+		return e.Start.Format("15:04")
+	}
+	return e.Start.Format("Mon 2 Jan 15:04")
+}
+
+// SameDay returns whether b is on the same day as a in a's time zone.
+func SameDay(a, b time.Time) bool {
+	y1, m1, d1 := a.Date()
+	y2, m2, d2 := b.In(a.Location()).Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
 
-		n.evMtx.Unlock()
+func organizer(e *calendar.Event) string {
+	if e.Organizer == nil || e.Organizer.Self {
+		return ""
 	}
+	return e.Organizer.Email
 }
 
 func attending(e *calendar.Event) bool {
@@ -175,21 +534,7 @@ func attending(e *calendar.Event) bool {
 func (n *Notifier) notifyWorker(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
 	for {
-		n.evMtx.Lock()
-		for id, e := range n.ev {
-			if e.End.Before(time.Now()) {
-				delete(n.ev, id)
-				continue
-			} else {
-				for _, r := range e.Reminders {
-					if !r.Notified && time.Until(e.Start) <= r.Before {
-						r.NotificationID = n.doNotify(e)
-						r.Notified = true
-					}
-				}
-			}
-		}
-		n.evMtx.Unlock()
+		n.checkReminders()
 
 		select {
 		case <-ticker.C:
@@ -200,65 +545,48 @@ func (n *Notifier) notifyWorker(ctx context.Context) {
 	}
 }
 
-func (n *Notifier) doNotify(e *Event) uint32 {
-	not := notify.Notification{
-		AppName: "gcal-notify",
-		// https://specifications.freedesktop.org/icon-naming-spec/latest/ar01s04.html
-		AppIcon: "x-office-calendar",
-		Summary: fmt.Sprintf("%s | %s", e.Start.Format("15:04"), e.Summary),
-		Body:    e.Description,
-		Actions: []notify.Action{
-			{Key: "default", Label: "Default"},
-		},
-		Hints: map[string]dbus.Variant{},
-	}
-	if e.Hangout != "" {
-		not.AppIcon = "camera-web"
-	}
-	id, err := n.notifier.SendNotification(not)
-	if err != nil {
-		log.Printf("Failed to send notification via dbus: %v", err)
-	} else {
-		config.Debug.Printf("Sent notification: summary=%q id=%d", not.Summary, id)
-	}
-
-	n.activeMtx.Lock()
-	n.active[id] = e
-	n.activeMtx.Unlock()
-	return id
-}
-
-func (n *Notifier) onAction(action *notify.ActionInvokedSignal) {
-	config.Debug.Printf("Notification action: key=%s id=%d", action.ActionKey, action.ID)
-	n.activeMtx.Lock()
-	defer n.activeMtx.Unlock()
-	e, ok := n.active[action.ID]
-	if ok {
-		if e.Hangout != "" {
-			browser.Open(e.Hangout)
-		} else if e.Link != "" {
-			browser.Open(e.Link)
+// checkReminders delivers the reminders which are due and drops events that
+// have ended.
+func (n *Notifier) checkReminders() {
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+
+	paused := time.Now().Before(n.pausedUntil)
+	for id, e := range n.ev {
+		if e.End.Before(time.Now()) {
+			if !paused {
+				n.ended(e)
+			}
+			delete(n.ev, id)
+			n.dirty = true
+			for _, o := range n.observers {
+				o.EventRemoved(e)
+			}
+			continue
+		} else if !paused {
+			for _, r := range e.Reminders {
+				if !r.Notified && time.Until(e.Start) <= r.Before {
+					r.NotificationID = n.send(n.reminderNotification(e, r, false))
+					r.Notified = true
+					n.dirty = true
+				}
+			}
+			n.checkOverlap(e)
+			n.checkEnd(e)
 		}
 	}
-}
-
-func (n *Notifier) onClosed(closer *notify.NotificationClosedSignal) {
-	config.Debug.Printf("Notification closed: reason=%v id=%d", closer.Reason, closer.ID)
-	n.activeMtx.Lock()
-	delete(n.active, closer.ID)
-	n.activeMtx.Unlock()
+	n.saveState()
 }
 
 func (n *Notifier) closeNotifications(e *Event) {
-	n.activeMtx.Lock()
-	defer n.activeMtx.Unlock()
-
 	for _, r := range e.Reminders {
 		if r.Notified {
-			n.notifier.CloseNotification(r.NotificationID)
-			delete(n.active, r.NotificationID)
+			n.close(r.NotificationID)
 		}
 	}
+	if e.EndReminderID != 0 {
+		n.close(e.EndReminderID)
+	}
 
 	select {
 	case n.checkNotifications <- struct{}{}:
@@ -270,28 +598,42 @@ type Reminder struct {
 	Before         time.Duration
 	Notified       bool
 	NotificationID uint32
+	Extra          bool // ad-hoc reminder, e.g. added by snoozing a notification
 }
 
 func (r *Reminder) String() string {
-	return fmt.Sprintf("{Before:%v Notified:%t}", r.Before, r.Notified)
+	return fmt.Sprintf("{Before:%v Notified:%t Extra:%t}", r.Before, r.Notified, r.Extra)
 }
 
-var eventCompareOption = cmp.FilterPath(func(p cmp.Path) bool {
-	switch p.String() {
-	case "Reminders.Notified", "Reminders.NotificationID":
-		return true // ignore
-	default:
-		return false
-	}
-}, cmp.Ignore())
+var eventCompareOption = cmp.Options{
+	cmp.FilterPath(func(p cmp.Path) bool {
+		switch p.String() {
+		case "Reminders.Notified", "Reminders.NotificationID", "EndReminderID", "OverlapNotified":
+			return true // ignore
+		default:
+			return false
+		}
+	}, cmp.Ignore()),
+	cmpopts.IgnoreSliceElements(func(r *Reminder) bool { return r.Extra }),
+}
 
 type Event struct {
+	CalendarID  string
+	ID          string
 	Summary     string
 	Description string
 	Start       time.Time
 	End         time.Time
-	Hangout     string
+	AllDay      bool
+	Conference  conference.Link
 	Link        string
 	Location    string
+	Response    string // own response status, e.g. "needsAction"
+	Organizer   string // e-mail address, unless organized by oneself
+	Prefix      string // prepended to the summary by rules
+	Urgency     string // set by rules, see config.Rule
 	Reminders   []*Reminder
+
+	EndReminderID   uint32 // notification of the EndReminder, if delivered
+	OverlapNotified bool   // the OverlapWarning was delivered
 }
@@ -1,12 +1,16 @@
 package events
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/conference"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
 )
 
 func TestEventComparison(t *testing.T) {
@@ -31,6 +35,10 @@ func TestEventComparison(t *testing.T) {
 		},
 	}
 
+	assert.True(t, cmp.Equal(e1, e2, eventCompareOption),
+		cmp.Diff(e1, e2, eventCompareOption))
+
+	e1.Reminders = append(e1.Reminders, &Reminder{Before: 2 * time.Minute, Extra: true})
 	assert.True(t, cmp.Equal(e1, e2, eventCompareOption),
 		cmp.Diff(e1, e2, eventCompareOption))
 
@@ -39,8 +47,486 @@ func TestEventComparison(t *testing.T) {
 		cmp.Diff(e1, e2, eventCompareOption))
 }
 
+// newTestNotifier returns a notifier without notification backend, keeping
+// its state in a temporary directory.
+func newTestNotifier(t *testing.T, svc *calendar.Service, cals ...config.Calendar) *Notifier {
+	prev := config.Cfg.StatePath
+	config.Cfg.StatePath = filepath.Join(t.TempDir(), "state.json")
+	t.Cleanup(func() { config.Cfg.StatePath = prev })
+
+	return NewNotifier(svc, cals)
+}
+
 func mustParseTime(t *testing.T, s string) time.Time {
 	ts, err := time.Parse(time.RFC3339, s)
 	require.NoError(t, err)
 	return ts
 }
+
+// testEvent returns a half-hour event with reminders the given number of
+// minutes before its start.
+func testEvent(id, summary string, start time.Time, minutes ...int64) *calendar.Event {
+	var overrides []*calendar.EventReminder
+	for _, m := range minutes {
+		overrides = append(overrides, &calendar.EventReminder{Minutes: m})
+	}
+	return &calendar.Event{
+		Id:        id,
+		Summary:   summary,
+		Start:     &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:       &calendar.EventDateTime{DateTime: start.Add(30 * time.Minute).Format(time.RFC3339)},
+		Reminders: &calendar.EventReminders{Overrides: overrides},
+	}
+}
+
+// testEvents returns a listing of the given events.
+func testEvents(items ...*calendar.Event) *calendar.Events {
+	return &calendar.Events{Items: items}
+}
+
+func TestUpdateNamespacesCalendars(t *testing.T) {
+	n := newTestNotifier(t, nil,
+		config.Calendar{ID: "me@example.com"},
+		config.Calendar{ID: "team@example.com", Reminders: []config.Duration{{D: time.Minute}}},
+	)
+	list := func(summary string) *calendar.Events {
+		return &calendar.Events{
+			DefaultReminders: []*calendar.EventReminder{{Minutes: 10}},
+			Items: []*calendar.Event{{
+				Id:        "abc",
+				Summary:   summary,
+				Start:     &calendar.EventDateTime{DateTime: "2022-05-12T12:00:00Z"},
+				End:       &calendar.EventDateTime{DateTime: "2022-05-12T12:30:00Z"},
+				Reminders: &calendar.EventReminders{UseDefault: true},
+			}},
+		}
+	}
+
+	n.update(&n.cals[0], list("personal"), true, time.Time{})
+	n.update(&n.cals[1], list("team"), true, time.Time{})
+	require.Len(t, n.ev, 2)
+	assert.Equal(t, "personal", n.ev["me@example.com/abc"].Summary)
+	assert.Equal(t, 10*time.Minute, n.ev["me@example.com/abc"].Reminders[0].Before)
+	assert.Equal(t, "team", n.ev["team@example.com/abc"].Summary)
+	assert.Equal(t, time.Minute, n.ev["team@example.com/abc"].Reminders[0].Before)
+
+	n.update(&n.cals[1], &calendar.Events{}, false, time.Time{})
+	require.Len(t, n.ev, 2)
+
+	n.update(&n.cals[1], &calendar.Events{Items: []*calendar.Event{{Id: "abc", Status: "cancelled"}}}, false, time.Time{})
+	require.Len(t, n.ev, 1)
+
+	n.update(&n.cals[0], &calendar.Events{}, true, time.Time{})
+	require.Len(t, n.ev, 0)
+}
+
+func TestUpdateAllDay(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com", Reminders: []config.Duration{{D: time.Minute}}})
+	n.update(&n.cals[0], &calendar.Events{
+		TimeZone:         "Europe/Berlin",
+		DefaultReminders: []*calendar.EventReminder{{Minutes: 10}},
+		Items: []*calendar.Event{{
+			Id:        "holiday",
+			Start:     &calendar.EventDateTime{Date: "2030-05-12"},
+			End:       &calendar.EventDateTime{Date: "2030-05-13"},
+			Reminders: &calendar.EventReminders{UseDefault: true},
+		}, {
+			Id:    "trip",
+			Start: &calendar.EventDateTime{Date: "2030-05-14", TimeZone: "America/New_York"},
+			End:   &calendar.EventDateTime{Date: "2030-05-16", TimeZone: "America/New_York"},
+			Reminders: &calendar.EventReminders{Overrides: []*calendar.EventReminder{
+				{Minutes: 24*60 + 7*60}, // two days before at 17:00
+			}},
+		}},
+	}, true, time.Time{})
+	require.Len(t, n.ev, 2)
+
+	e := n.ev["me@example.com/holiday"]
+	assert.True(t, e.AllDay)
+	assert.Equal(t, "2030-05-12T00:00:00+02:00", e.Start.Format(time.RFC3339))
+	assert.Equal(t, "2030-05-13T00:00:00+02:00", e.End.Format(time.RFC3339))
+	require.Len(t, e.Reminders, 1)
+	assert.Equal(t, 7*time.Hour, e.Reminders[0].Before)
+
+	e = n.ev["me@example.com/trip"]
+	assert.True(t, e.AllDay)
+	assert.Equal(t, "2030-05-14T00:00:00-04:00", e.Start.Format(time.RFC3339))
+	require.Len(t, e.Reminders, 1)
+	assert.Equal(t, "2030-05-12T17:00:00-04:00", e.Start.Add(-e.Reminders[0].Before).Format(time.RFC3339))
+}
+
+// memSink records the notifications delivered to it.
+type memSink struct {
+	sent    []*Notification
+	updated []*Notification
+	closed  []uint32
+}
+
+func (s *memSink) Send(n *Notification) error {
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func (s *memSink) Update(n *Notification) error {
+	s.updated = append(s.updated, n)
+	return nil
+}
+
+func (s *memSink) Close(id uint32) error {
+	s.closed = append(s.closed, id)
+	return nil
+}
+
+// memObserver records the events it is informed about.
+type memObserver struct {
+	added, changed, removed []string
+}
+
+func (o *memObserver) EventAdded(e *Event)   { o.added = append(o.added, e.ID) }
+func (o *memObserver) EventChanged(e *Event) { o.changed = append(o.changed, e.ID) }
+func (o *memObserver) EventRemoved(e *Event) { o.removed = append(o.removed, e.ID) }
+
+func TestCheckReminders(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com", Label: "Personal"})
+	desktop, other := &memSink{}, &memSink{}
+	n.AddSink(desktop)
+	n.AddSink(other)
+
+	start := time.Now().Add(5 * time.Minute)
+	n.ev["me@example.com/abc"] = &Event{
+		CalendarID: "me@example.com",
+		ID:         "abc",
+		Summary:    "Standup",
+		Start:      start,
+		End:        start.Add(15 * time.Minute),
+		Conference: conference.Link{URL: "https://meet.google.com/abc-defg-hij", Provider: "Google Meet", Icon: "camera-web"},
+		Reminders: []*Reminder{
+			{Before: 10 * time.Minute},
+			{Before: time.Minute},
+		},
+	}
+	n.ev["me@example.com/ended"] = &Event{CalendarID: "me@example.com", End: time.Now().Add(-time.Second)}
+
+	n.checkReminders()
+	require.Len(t, desktop.sent, 1)
+	require.Len(t, other.sent, 1)
+	not := desktop.sent[0]
+	assert.Equal(t, start.Format("15:04")+" | Personal | Standup", not.Summary)
+	assert.Equal(t, "camera-web", not.Icon)
+	assert.Contains(t, not.Actions, Action{Key: "join", Label: "Join Google Meet"})
+	assert.NotContains(t, n.ev, "me@example.com/ended")
+
+	e := n.ev["me@example.com/abc"]
+	assert.True(t, e.Reminders[0].Notified)
+	assert.Equal(t, not.ID, e.Reminders[0].NotificationID)
+	assert.False(t, e.Reminders[1].Notified)
+
+	// already delivered reminders do not fire again
+	n.checkReminders()
+	assert.Len(t, desktop.sent, 1)
+
+	n.OnAction(not.ID, snoozeActionPrefix+snoozeUntilStart)
+	assert.Equal(t, []uint32{not.ID}, desktop.closed)
+	assert.Equal(t, []uint32{not.ID}, other.closed)
+	require.Len(t, e.Reminders, 3)
+	assert.True(t, e.Reminders[2].Extra)
+}
+
+func TestUpdateReplacesNotifications(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	sink := &memSink{}
+	n.AddSink(sink)
+
+	start := time.Now().Add(5 * time.Minute).Truncate(time.Minute)
+	standup := testEvent("abc", "Standup", start, 10, 1)
+	standup.Location = "Room 1"
+	n.update(&n.cals[0], testEvents(standup), true, time.Time{})
+	n.checkReminders()
+	require.Len(t, sink.sent, 1)
+	id := sink.sent[0].ID
+
+	// a changed location updates the notification without firing again
+	standup.Location = "Room 2"
+	n.update(&n.cals[0], testEvents(standup), true, time.Time{})
+	require.Len(t, sink.updated, 1)
+	assert.Equal(t, id, sink.updated[0].ID)
+	assert.NotContains(t, sink.updated[0].Summary, "(moved)")
+	assert.Contains(t, sink.updated[0].Body, "Room 2")
+	n.checkReminders()
+	assert.Len(t, sink.sent, 1)
+	assert.Empty(t, sink.closed)
+
+	// moving the event later re-arms the 10 minute reminder
+	later := start.Add(30 * time.Minute)
+	moved := testEvent("abc", "Standup", later, 10, 1)
+	moved.Location = "Room 2"
+	n.update(&n.cals[0], testEvents(moved), true, time.Time{})
+	require.Len(t, sink.updated, 2)
+	assert.Equal(t, id, sink.updated[1].ID)
+	assert.Equal(t, later.Format("15:04")+" (moved) | Standup", sink.updated[1].Summary)
+	e := n.ev["me@example.com/abc"]
+	assert.False(t, e.Reminders[0].Notified)
+	require.Len(t, e.Reminders, 3)
+	assert.True(t, e.Reminders[2].Extra)
+	assert.Equal(t, id, e.Reminders[2].NotificationID)
+
+	// moving it back takes over the earlier notification
+	n.update(&n.cals[0], testEvents(standup), true, time.Time{})
+	require.Len(t, sink.updated, 3)
+	e = n.ev["me@example.com/abc"]
+	require.Len(t, e.Reminders, 2)
+	assert.True(t, e.Reminders[0].Notified)
+	assert.Equal(t, id, e.Reminders[0].NotificationID)
+	n.checkReminders()
+	assert.Len(t, sink.sent, 1)
+}
+
+func TestUpdateSkipsClosedNotifications(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	sink := &memSink{}
+	n.AddSink(sink)
+
+	start := time.Now().Add(5 * time.Minute).Truncate(time.Minute)
+	standup := testEvent("abc", "Standup", start, 10)
+	standup.Location = "Room 1"
+	n.update(&n.cals[0], testEvents(standup), true, time.Time{})
+	n.checkReminders()
+	require.Len(t, sink.sent, 1)
+	n.OnClosed(sink.sent[0].ID)
+
+	// the dismissed reminder neither comes back nor fires again
+	standup.Location = "Room 2"
+	n.update(&n.cals[0], testEvents(standup), true, time.Time{})
+	n.checkReminders()
+	assert.Empty(t, sink.updated)
+	assert.Len(t, sink.sent, 1)
+	assert.True(t, n.ev["me@example.com/abc"].Reminders[0].Notified)
+
+	// without a notification to update, a move is announced separately
+	n.update(&n.cals[0], testEvents(testEvent("abc", "Standup", start.Add(2*time.Minute), 10)), true, time.Time{})
+	assert.Empty(t, sink.updated)
+	require.Len(t, sink.sent, 2)
+	assert.Equal(t, "Moved: Standup", sink.sent[1].Summary)
+}
+
+func TestUpdateIncrementalWindow(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	sink := &memSink{}
+	n.AddSink(sink)
+	obs := &memObserver{}
+	n.AddObserver(obs)
+
+	now := time.Now().Truncate(time.Minute)
+	until := now.Add(48 * time.Hour)
+	n.update(&n.cals[0], testEvents(testEvent("a", "Review a", now.Add(time.Hour))), true, until)
+	require.Len(t, n.ev, 1)
+
+	// changes of ended events and of events beyond the window are ignored,
+	// and an event moved beyond the window is dropped
+	n.update(&n.cals[0], testEvents(
+		testEvent("ended", "Review ended", now.Add(-time.Hour)),
+		testEvent("later", "Review later", until.Add(time.Hour)),
+		testEvent("a", "Review a", until.Add(time.Hour)),
+	), false, until)
+	assert.Empty(t, n.ev)
+	assert.Empty(t, sink.sent)
+	assert.Equal(t, []string{"a"}, obs.added)
+	assert.Empty(t, obs.changed)
+	assert.Equal(t, []string{"a"}, obs.removed)
+}
+
+func TestUpdateNotifiesChanges(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com", Label: "Work"})
+	sink := &memSink{}
+	n.AddSink(sink)
+
+	start := time.Now().Add(30 * time.Minute).Truncate(time.Minute)
+	n.update(&n.cals[0], testEvents(
+		testEvent("a", "Review a", start), testEvent("b", "Review b", start), testEvent("c", "Review c", start.Add(2*time.Hour)),
+	), true, time.Time{})
+	assert.Empty(t, sink.sent)
+
+	moved := start.Add(24 * time.Hour)
+	n.update(&n.cals[0], testEvents(
+		testEvent("a", "Review a", moved), &calendar.Event{Id: "b", Status: "cancelled"}, &calendar.Event{Id: "c", Status: "cancelled"},
+	), false, moved.Add(time.Hour))
+	require.Len(t, sink.sent, 2)
+	assert.Equal(t, "Moved: Work | Review a", sink.sent[0].Summary)
+	assert.Equal(t, start.Format("15:04")+" → "+moved.Format("Mon 2 Jan 15:04"), sink.sent[0].Body)
+	assert.Equal(t, "Cancelled: Work | Review b", sink.sent[1].Summary)
+
+	// the moved event is no longer imminent
+	n.update(&n.cals[0], &calendar.Events{}, true, time.Time{})
+	assert.Len(t, sink.sent, 2)
+}
+
+func TestReminderStyles(t *testing.T) {
+	prev := config.Cfg.ReminderStyles
+	config.Cfg.ReminderStyles = []config.ReminderStyle{
+		{Before: config.Duration{D: time.Minute}, Urgency: "critical", SoundName: "alarm-clock-elapsed", Resident: true},
+		{Before: config.Duration{D: 5 * time.Minute}, ExpireTimeout: config.Timeout{Default: true}},
+		{Before: config.Duration{D: 10 * time.Minute}, ExpireTimeout: config.Timeout{D: 30 * time.Second}, Category: "x-gnome.calendar"},
+	}
+	t.Cleanup(func() { config.Cfg.ReminderStyles = prev })
+
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	e := &Event{CalendarID: "me@example.com", Summary: "Standup", Start: time.Now().Add(time.Hour)}
+
+	not := n.reminderNotification(e, &Reminder{Before: time.Minute}, false)
+	assert.Equal(t, UrgencyCritical, not.Urgency)
+	assert.Equal(t, "alarm-clock-elapsed", not.SoundName)
+	assert.True(t, not.Resident)
+	assert.Zero(t, not.ExpireTimeout)
+
+	not = n.reminderNotification(e, &Reminder{Before: 5 * time.Minute}, false)
+	assert.True(t, not.ServerTimeout)
+
+	not = n.reminderNotification(e, &Reminder{Before: 10 * time.Minute}, false)
+	assert.Equal(t, UrgencyNormal, not.Urgency)
+	assert.Equal(t, 30*time.Second, not.ExpireTimeout)
+	assert.Equal(t, "x-gnome.calendar", not.Category)
+
+	not = n.reminderNotification(e, &Reminder{Before: time.Hour}, false)
+	assert.Empty(t, not.Category)
+	assert.Zero(t, not.ExpireTimeout)
+	assert.False(t, not.ServerTimeout)
+}
+
+func TestUpdateRules(t *testing.T) {
+	prev := config.Cfg.Rules
+	config.Cfg.Rules = []config.Rule{
+		{Name: "free", Transparency: "transparent", Skip: true},
+		{Name: "optional", Response: "tentative", Prefix: "(optional) ", Urgency: "low",
+			Reminders: []config.Duration{{D: 2 * time.Minute}}},
+	}
+	t.Cleanup(func() { config.Cfg.Rules = prev })
+
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	start := mustParseTime(t, "2030-05-12T12:00:00Z")
+	free, optional := testEvent("free", "Sync", start), testEvent("optional", "Sync", start)
+	free.Reminders.UseDefault, optional.Reminders.UseDefault = true, true
+	free.Transparency = "transparent"
+	optional.Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: "tentative"}}
+
+	n.update(&n.cals[0], &calendar.Events{
+		DefaultReminders: []*calendar.EventReminder{{Minutes: 10}},
+		Items:            []*calendar.Event{free, optional},
+	}, true, time.Time{})
+	require.Len(t, n.ev, 1)
+	e := n.ev["me@example.com/optional"]
+	require.Len(t, e.Reminders, 1)
+	assert.Equal(t, 2*time.Minute, e.Reminders[0].Before)
+
+	not := n.reminderNotification(e, e.Reminders[0], false)
+	assert.Equal(t, "12:00 | (optional) Sync", not.Summary)
+	assert.Equal(t, UrgencyLow, not.Urgency)
+}
+
+func TestUpdateLocalReminders(t *testing.T) {
+	prevReminders, prevMerge := config.Cfg.Reminders, config.Cfg.MergeReminders
+	config.Cfg.Reminders = []config.Duration{{D: time.Minute}}
+	config.Cfg.MergeReminders = true
+	t.Cleanup(func() { config.Cfg.Reminders, config.Cfg.MergeReminders = prevReminders, prevMerge })
+
+	n := newTestNotifier(t, nil,
+		config.Calendar{ID: "me@example.com"},
+		config.Calendar{ID: "team@example.com", Reminders: []config.Duration{{D: 10 * time.Minute}, {D: 2 * time.Minute}}, MergeReminders: true},
+	)
+	events := &calendar.Events{
+		DefaultReminders: []*calendar.EventReminder{{Minutes: 10}},
+		Items: []*calendar.Event{{
+			Id:        "abc",
+			Start:     &calendar.EventDateTime{DateTime: "2030-05-12T12:00:00Z"},
+			End:       &calendar.EventDateTime{DateTime: "2030-05-12T12:30:00Z"},
+			Reminders: &calendar.EventReminders{UseDefault: true},
+		}, {
+			Id:        "none",
+			Start:     &calendar.EventDateTime{DateTime: "2030-05-12T14:00:00Z"},
+			End:       &calendar.EventDateTime{DateTime: "2030-05-12T14:30:00Z"},
+			Reminders: &calendar.EventReminders{},
+		}},
+	}
+	n.update(&n.cals[0], events, true, time.Time{})
+	n.update(&n.cals[1], events, true, time.Time{})
+
+	offsets := func(key string) (d []time.Duration) {
+		for _, r := range n.ev[key].Reminders {
+			d = append(d, r.Before)
+		}
+		return
+	}
+	assert.Equal(t, []time.Duration{10 * time.Minute, time.Minute}, offsets("me@example.com/abc"))
+	assert.Equal(t, []time.Duration{time.Minute}, offsets("me@example.com/none"))
+	assert.Equal(t, []time.Duration{10 * time.Minute, time.Minute, 2 * time.Minute}, offsets("team@example.com/abc"))
+}
+
+func TestControl(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	sink := &memSink{}
+	n.AddSink(sink)
+	obs := &memObserver{}
+	n.AddObserver(obs)
+
+	start := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+	n.update(&n.cals[0], testEvents(
+		testEvent("b", "Review", start.Add(time.Hour), 10), testEvent("a", "Standup", start, 10),
+	), true, time.Time{})
+	n.update(&n.cals[0], testEvents(testEvent("a", "Daily standup", start, 10)), true, time.Time{})
+	assert.Equal(t, []string{"b", "a"}, obs.added)
+	assert.Equal(t, []string{"a"}, obs.changed)
+	assert.Equal(t, []string{"b"}, obs.removed)
+
+	n.update(&n.cals[0], testEvents(
+		testEvent("a", "Daily standup", start, 10), testEvent("b", "Review", start.Add(time.Hour), 10),
+	), true, time.Time{})
+	upcoming := n.Upcoming()
+	require.Len(t, upcoming, 2)
+	assert.Equal(t, "a", upcoming[0].ID)
+	assert.Equal(t, "b", upcoming[1].ID)
+
+	n.Pause(time.Hour)
+	assert.False(t, n.PausedUntil().IsZero())
+	n.checkReminders()
+	assert.Empty(t, sink.sent)
+	n.Resume()
+	assert.True(t, n.PausedUntil().IsZero())
+	n.checkReminders()
+	require.Len(t, sink.sent, 1)
+
+	assert.Equal(t, 1, n.SnoozeAll(2*time.Minute))
+	assert.Equal(t, []uint32{sink.sent[0].ID}, sink.closed)
+	e := n.ev["me@example.com/a"]
+	require.Len(t, e.Reminders, 2)
+	assert.True(t, e.Reminders[1].Extra)
+	assert.Equal(t, 0, n.SnoozeAll(2*time.Minute))
+}
+
+func TestUpdateRuleRemindersSkipAllDay(t *testing.T) {
+	prev := config.Cfg.Rules
+	config.Cfg.Rules = []config.Rule{{Name: "all", Reminders: []config.Duration{{D: 5 * time.Minute}}, MergeReminders: true}}
+	t.Cleanup(func() { config.Cfg.Rules = prev })
+
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	n.update(&n.cals[0], testEvents(
+		testEvent("timed", "Sync", mustParseTime(t, "2030-05-12T12:00:00Z"), 10, 10),
+		&calendar.Event{
+			Id:    "holiday",
+			Start: &calendar.EventDateTime{Date: "2030-05-12"},
+			End:   &calendar.EventDateTime{Date: "2030-05-13"},
+			Reminders: &calendar.EventReminders{Overrides: []*calendar.EventReminder{
+				{Minutes: 7 * 60}, {Minutes: 7 * 60},
+			}},
+		},
+	), true, time.Time{})
+
+	offsets := func(key string) (d []time.Duration) {
+		for _, r := range n.ev[key].Reminders {
+			d = append(d, r.Before)
+		}
+		return
+	}
+	// duplicates are dropped also without local reminders, and rules, like
+	// the other local reminders, only apply to timed events
+	assert.Equal(t, []time.Duration{10 * time.Minute, 5 * time.Minute}, offsets("me@example.com/timed"))
+	assert.Equal(t, []time.Duration{7 * time.Hour}, offsets("me@example.com/holiday"))
+}
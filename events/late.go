@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+const lateActionKey = "late"
+
+// Messenger delivers a message about an event to its organizer.
+type Messenger interface {
+	MessageOrganizer(ctx context.Context, e *Event, text string) error
+}
+
+// SetMessenger offers an action on reminders to tell the organizer about
+// running late through m. It must not be called once polling has started.
+func (n *Notifier) SetMessenger(m Messenger) {
+	n.messenger = m
+}
+
+// commentMessenger adds messages to the comment of one's response to an event,
+// which is shown to the organizer among the guests.
+type commentMessenger struct {
+	svc *calendar.Service
+}
+
+// NewCommentMessenger returns a Messenger commenting on events. It requires
+// write access to the calendar.
+func NewCommentMessenger(svc *calendar.Service) Messenger {
+	return &commentMessenger{svc: svc}
+}
+
+func (c *commentMessenger) MessageOrganizer(ctx context.Context, e *Event, text string) error {
+	return patchSelf(ctx, c.svc, e.CalendarID, e.ID, func(a *calendar.EventAttendee) {
+		if a.Comment != "" {
+			text = a.Comment + "\n" + text
+		}
+		a.Comment = text
+	})
+}
+
+func lateAction() Action {
+	return Action{Key: lateActionKey, Label: "Running late (" + formatDuration(config.Cfg.RunningLate.Delay.D) + ")"}
+}
+
+// runningLate tells the organizer of e about running late.
+func (n *Notifier) runningLate(e *Event) {
+	data := struct {
+		Summary string
+		Start   time.Time
+		Delay   string
+	}{e.Summary, e.Start, formatDuration(config.Cfg.RunningLate.Delay.D)}
+	var text strings.Builder
+	if err := config.Cfg.RunningLate.Message.Execute(&text, data); err != nil {
+		log.Printf("Failed to render running late message: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := n.messenger.MessageOrganizer(ctx, e, text.String()); err != nil {
+		log.Printf("Failed to tell organizer of %q about running late: %v", e.Summary, err)
+		return
+	}
+	config.Debug.Printf("Told %s about running late for %q", e.Organizer, e.Summary)
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+// memMessenger records the messages sent through it.
+type memMessenger struct {
+	to   []string
+	text []string
+}
+
+func (m *memMessenger) MessageOrganizer(ctx context.Context, e *Event, text string) error {
+	m.to = append(m.to, e.Organizer)
+	m.text = append(m.text, text)
+	return nil
+}
+
+func TestRunningLate(t *testing.T) {
+	prev := config.Cfg.RunningLate
+	config.Cfg.RunningLate = config.RunningLate{
+		Via:     "slack",
+		Delay:   config.Duration{D: 10 * time.Minute},
+		Message: config.Template{Template: template.Must(template.New("").Parse(config.DefaultRunningLateMessage))},
+	}
+	t.Cleanup(func() { config.Cfg.RunningLate = prev })
+
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	m := &memMessenger{}
+	n.SetMessenger(m)
+
+	e := &Event{CalendarID: "me@example.com", ID: "abc", Summary: "Planning", Organizer: "lead@example.com",
+		Start: time.Now().Add(time.Minute)}
+	not := n.reminderNotification(e, &Reminder{Before: time.Minute}, false)
+	assert.Contains(t, not.Actions, Action{Key: "late", Label: "Running late (10 min)"})
+
+	n.runningLate(e)
+	require.Len(t, m.text, 1)
+	assert.Equal(t, "lead@example.com", m.to[0])
+	assert.Equal(t, "Sorry, I'm running about 10 min late for Planning.", m.text[0])
+
+	// no action for one's own events
+	e.Organizer = ""
+	not = n.reminderNotification(e, &Reminder{Before: time.Minute}, false)
+	assert.NotContains(t, not.Actions, Action{Key: "late", Label: "Running late (10 min)"})
+}
+
+func TestCommentMessenger(t *testing.T) {
+	var patched *calendar.Event
+	m := NewCommentMessenger(newRSVPService(t, &patched))
+
+	e := &Event{CalendarID: "me@example.com", ID: "abc"}
+	require.NoError(t, m.MessageOrganizer(context.Background(), e, "Running late"))
+	require.NotNil(t, patched)
+	// the message is added to the existing comment
+	assert.Equal(t, "Joining remotely\nRunning late", patched.Attendees[1].Comment)
+	assert.Equal(t, "needsAction", patched.Attendees[1].ResponseStatus)
+}
@@ -0,0 +1,388 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/browser"
+	"github.com/svenschwermer/gcal-notify/clipboard"
+	"github.com/svenschwermer/gcal-notify/config"
+)
+
+// Sink delivers notifications to the user, e.g. as desktop notifications.
+// Notifications are identified by the ID assigned by the Notifier; sinks keep
+// track of their own identifiers. Sinks are called with the Notifier's event
+// lock held and must therefore not block for long.
+type Sink interface {
+	// Send delivers a new notification.
+	Send(n *Notification) error
+	// Update replaces the content of a notification delivered earlier.
+	Update(n *Notification) error
+	// Close withdraws a notification delivered earlier.
+	Close(id uint32) error
+}
+
+// Handler receives the user's interactions with notifications. It is
+// implemented by the Notifier and passed to interactive sinks.
+type Handler interface {
+	// OnAction is called when the user invoked an action of a notification.
+	OnAction(id uint32, key string)
+	// OnClosed is called when a notification was closed by the user or has
+	// expired.
+	OnClosed(id uint32)
+}
+
+// Observer is informed about changes of the upcoming events. It is called with
+// the Notifier's event lock held and must therefore not block for long.
+type Observer interface {
+	EventAdded(e *Event)
+	EventChanged(e *Event)
+	EventRemoved(e *Event)
+}
+
+type Urgency int
+
+const (
+	UrgencyNormal Urgency = iota
+	UrgencyLow
+	UrgencyCritical
+)
+
+type Action struct {
+	Key   string
+	Label string
+}
+
+// Notification is a message about an event.
+type Notification struct {
+	ID      uint32
+	Event   *Event
+	Summary string
+	Body    string
+	// https://specifications.freedesktop.org/icon-naming-spec/latest/ar01s04.html
+	Icon          string
+	Urgency       Urgency
+	Actions       []Action
+	ExpireTimeout time.Duration // zero never expires
+	ServerTimeout bool          // leave the timeout to the notification daemon
+	Category      string
+	SoundName     string
+	Resident      bool
+	Transient     bool
+}
+
+// reminderNotification builds the notification of reminder r of e. If moved
+// is set, the start time is marked as changed.
+func (n *Notifier) reminderNotification(e *Event, r *Reminder, moved bool) *Notification {
+	cal := n.calendar(e.CalendarID)
+	not := &Notification{
+		Event: e,
+		Body:  e.Description,
+		Icon:  "x-office-calendar",
+		Actions: []Action{
+			{Key: "default", Label: "Default"},
+		},
+	}
+	if e.Conference.URL != "" {
+		join := "Join meeting"
+		if e.Conference.Provider != "" {
+			join = "Join " + e.Conference.Provider
+		}
+		not.Actions = append(not.Actions,
+			Action{Key: "join", Label: join},
+			Action{Key: "copy", Label: "Copy meeting link"})
+	}
+	if e.Link != "" {
+		not.Actions = append(not.Actions, Action{Key: "open", Label: "Open in Calendar"})
+	}
+	if e.mapsURL() != "" {
+		not.Actions = append(not.Actions, Action{Key: "map", Label: "Show location"})
+	}
+	if n.messenger != nil && e.Organizer != "" {
+		not.Actions = append(not.Actions, lateAction())
+	}
+	if n.rsvp && e.Response == "needsAction" {
+		not.Actions = append(not.Actions, rsvpActions...)
+	}
+	if e.Location != "" && e.Location != e.Conference.URL {
+		not.Body = strings.TrimSpace("Location: " + e.Location + "\n\n" + e.Description)
+	}
+	when := e.Start.Format("15:04")
+	if e.AllDay {
+		// all-day reminders typically fire the day before
+		when = e.Start.Format("Mon 2 Jan")
+		not.Icon = "appointment-soon"
+		not.Urgency = UrgencyLow
+	}
+	if moved {
+		when += " (moved)"
+	}
+	not.Summary = fmt.Sprintf("%s | %s%s", when, e.Prefix, e.Summary)
+	if cal.Label != "" {
+		not.Summary = fmt.Sprintf("%s | %s | %s%s", when, cal.Label, e.Prefix, e.Summary)
+	}
+	if cal.Icon != "" {
+		not.Icon = cal.Icon
+	}
+	if e.Conference.Icon != "" {
+		not.Icon = e.Conference.Icon
+	}
+	if style := reminderStyle(r); style != nil {
+		if u, ok := parseUrgency(style.Urgency); ok {
+			not.Urgency = u
+		}
+		not.ExpireTimeout = style.ExpireTimeout.D
+		not.ServerTimeout = style.ExpireTimeout.Default
+		not.Category = style.Category
+		not.SoundName = style.SoundName
+		not.Resident = style.Resident
+		not.Transient = style.Transient
+	}
+	if u, ok := parseUrgency(e.Urgency); ok {
+		not.Urgency = u
+	}
+	for _, d := range config.Cfg.SnoozeDurations {
+		not.Actions = append(not.Actions, Action{
+			Key:   snoozeActionPrefix + d.D.String(),
+			Label: "Snooze " + formatDuration(d.D),
+		})
+	}
+	if time.Until(e.Start) > 0 {
+		not.Actions = append(not.Actions, Action{
+			Key:   snoozeActionPrefix + snoozeUntilStart,
+			Label: "Until start",
+		})
+	}
+	return not
+}
+
+func parseUrgency(s string) (Urgency, bool) {
+	switch s {
+	case "low":
+		return UrgencyLow, true
+	case "normal":
+		return UrgencyNormal, true
+	case "critical":
+		return UrgencyCritical, true
+	}
+	return UrgencyNormal, false
+}
+
+// reminderStyle returns the configured style of r, if any.
+func reminderStyle(r *Reminder) *config.ReminderStyle {
+	for i, s := range config.Cfg.ReminderStyles {
+		if r.Before <= s.Before.D {
+			return &config.Cfg.ReminderStyles[i]
+		}
+	}
+	return nil
+}
+
+// notifyChange tells about a change of an imminent event, e.g.
+// "Cancelled: Standup". The body defaults to the time of the event.
+func (n *Notifier) notifyChange(e *Event, change, detail string) {
+	if detail == "" {
+		detail = fmt.Sprintf("%s – %s", e.Start.Format("15:04"), e.End.Format("15:04"))
+	}
+	n.notice(e, change, detail, "appointment-missed")
+}
+
+// notice delivers a notification about e that is not a reminder, e.g.
+// "Cancelled: Standup", and returns its ID.
+func (n *Notifier) notice(e *Event, what, body, icon string) uint32 {
+	summary := e.Prefix + e.Summary
+	if cal := n.calendar(e.CalendarID); cal.Label != "" {
+		summary = cal.Label + " | " + summary
+	}
+	not := &Notification{
+		Event:   e,
+		Summary: fmt.Sprintf("%s: %s", what, summary),
+		Body:    body,
+		Icon:    icon,
+	}
+	if e.Link != "" {
+		not.Actions = append(not.Actions, Action{Key: "open", Label: "Open in Calendar"})
+	}
+	return n.send(not)
+}
+
+// send delivers a notification through all sinks and returns its ID.
+func (n *Notifier) send(not *Notification) uint32 {
+	n.activeMtx.Lock()
+	n.lastID++
+	not.ID = n.lastID
+	n.active[not.ID] = not.Event
+	n.activeMtx.Unlock()
+
+	for _, s := range n.sinks {
+		if err := s.Send(not); err != nil {
+			log.Printf("Failed to send notification %q via %T: %v", not.Summary, s, err)
+		}
+	}
+	config.Debug.Printf("Sent notification: summary=%q id=%d", not.Summary, not.ID)
+	return not.ID
+}
+
+// replace updates a notification delivered earlier in all sinks, unless it is
+// no longer shown, e.g. as it was dismissed. It returns whether it was shown.
+func (n *Notifier) replace(not *Notification) bool {
+	n.activeMtx.Lock()
+	_, shown := n.active[not.ID]
+	if shown {
+		n.active[not.ID] = not.Event
+	}
+	n.activeMtx.Unlock()
+	if !shown {
+		return false
+	}
+
+	for _, s := range n.sinks {
+		if err := s.Update(not); err != nil {
+			log.Printf("Failed to update notification %q via %T: %v", not.Summary, s, err)
+		}
+	}
+	config.Debug.Printf("Updated notification: summary=%q id=%d", not.Summary, not.ID)
+	return true
+}
+
+// close withdraws a notification from all sinks.
+func (n *Notifier) close(id uint32) {
+	n.activeMtx.Lock()
+	delete(n.active, id)
+	n.activeMtx.Unlock()
+
+	for _, s := range n.sinks {
+		if err := s.Close(id); err != nil {
+			log.Printf("Failed to close notification %d via %T: %v", id, s, err)
+		}
+	}
+}
+
+func (n *Notifier) OnAction(id uint32, key string) {
+	config.Debug.Printf("Notification action: key=%s id=%d", key, id)
+	n.activeMtx.Lock()
+	e, ok := n.active[id]
+	n.activeMtx.Unlock()
+	if !ok {
+		return
+	}
+
+	if snooze, ok := strings.CutPrefix(key, snoozeActionPrefix); ok {
+		n.close(id)
+		n.snooze(e, snooze)
+		return
+	}
+	if status, ok := strings.CutPrefix(key, rsvpActionPrefix); ok {
+		go n.respond(e, status)
+		return
+	}
+	switch key {
+	case lateActionKey:
+		go n.runningLate(e)
+	case "join":
+		browser.Open(e.Conference.URL)
+	case "open":
+		browser.Open(e.Link)
+	case "map":
+		browser.Open(e.mapsURL())
+	case "copy":
+		if !clipboard.Copy(e.Conference.URL) {
+			log.Printf("Failed to copy meeting link of %q to clipboard", e.Summary)
+		}
+	default:
+		if e.Conference.URL != "" {
+			browser.Open(e.Conference.URL)
+		} else if e.Link != "" {
+			browser.Open(e.Link)
+		}
+	}
+}
+
+func (n *Notifier) OnClosed(id uint32) {
+	config.Debug.Printf("Notification closed: id=%d", id)
+	n.activeMtx.Lock()
+	delete(n.active, id)
+	n.activeMtx.Unlock()
+}
+
+const (
+	snoozeActionPrefix = "snooze:"
+	snoozeUntilStart   = "start"
+)
+
+// snooze adds an extra reminder to the event which fires after the given
+// duration or, for snoozeUntilStart, at its start.
+func (n *Notifier) snooze(e *Event, d string) {
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+
+	// the event may have been replaced since the notification was sent
+	e, ok := n.ev[eventKey(e.CalendarID, e.ID)]
+	if !ok {
+		return
+	}
+	r := &Reminder{Extra: true}
+	if d != snoozeUntilStart {
+		dur, err := time.ParseDuration(d)
+		if err != nil {
+			log.Printf("Invalid snooze duration %q: %v", d, err)
+			return
+		}
+		r.Before = time.Until(e.Start) - dur
+	}
+	config.Debug.Printf("Snoozed event %q: reminder=%v", e.Summary, r)
+	e.Reminders = append(e.Reminders, r)
+	n.dirty = true
+	n.saveState()
+
+	select {
+	case n.checkNotifications <- struct{}{}:
+	default:
+	}
+}
+
+// SnoozeAll snoozes all shown reminders by d and returns their number.
+func (n *Notifier) SnoozeAll(d time.Duration) int {
+	type shown struct {
+		id uint32
+		e  *Event
+	}
+	var snooze []shown
+	n.evMtx.Lock()
+	n.activeMtx.Lock()
+	for _, e := range n.ev {
+		for _, r := range e.Reminders {
+			if _, ok := n.active[r.NotificationID]; ok && r.Notified {
+				snooze = append(snooze, shown{r.NotificationID, e})
+			}
+		}
+	}
+	n.activeMtx.Unlock()
+	n.evMtx.Unlock()
+
+	for _, s := range snooze {
+		n.close(s.id)
+		n.snooze(s.e, d.String())
+	}
+	return len(snooze)
+}
+
+// formatDuration formats whole minutes as e.g. "5 min".
+func formatDuration(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%d min", d/time.Minute)
+	}
+	return d.String()
+}
+
+// mapsURL returns a link to the event's location on a map, or an empty string
+// if the event has no physical location.
+func (e *Event) mapsURL() string {
+	if e.Location == "" || strings.HasPrefix(e.Location, "http://") || strings.HasPrefix(e.Location, "https://") {
+		return ""
+	}
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(e.Location)
+}
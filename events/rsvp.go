@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+const rsvpActionPrefix = "rsvp:"
+
+// rsvpActions are offered on reminders of unanswered invitations. The keys
+// carry the attendee response status.
+var rsvpActions = []Action{
+	{Key: rsvpActionPrefix + "accepted", Label: "Accept"},
+	{Key: rsvpActionPrefix + "tentative", Label: "Tentative"},
+	{Key: rsvpActionPrefix + "declined", Label: "Decline"},
+}
+
+// EnableRSVP offers actions to answer unanswered invitations on reminders. It
+// requires write access to the calendar and must not be called once polling
+// has started.
+func (n *Notifier) EnableRSVP() {
+	n.rsvp = true
+}
+
+// respond sets the own response status to e and refreshes the events. The
+// cached event is updated beforehand, so that the refresh neither re-sends its
+// reminders nor tells about the user's own response.
+func (n *Notifier) respond(e *Event, status string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := n.patchResponse(ctx, e.CalendarID, e.ID, status); err != nil {
+		log.Printf("Failed to respond to %q: %v", e.Summary, err)
+		return
+	}
+	config.Debug.Printf("Responded %s to %q", status, e.Summary)
+
+	n.evMtx.Lock()
+	id := eventKey(e.CalendarID, e.ID)
+	if e, ok := n.ev[id]; ok {
+		if status == "declined" {
+			n.removeEvent(id, e)
+		} else {
+			e.Response = status
+			n.dirty = true
+		}
+		n.saveState()
+	}
+	n.evMtx.Unlock()
+	n.Refresh()
+}
+
+func (n *Notifier) patchResponse(ctx context.Context, calID, eventID, status string) error {
+	return patchSelf(ctx, n.svc, calID, eventID, func(a *calendar.EventAttendee) {
+		a.ResponseStatus = status
+	})
+}
+
+// patchSelf modifies one's own attendee entry of an event.
+func patchSelf(ctx context.Context, svc *calendar.Service, calID, eventID string, modify func(*calendar.EventAttendee)) error {
+	event, err := svc.Events.Get(calID, eventID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to get event: %w", err)
+	}
+	found := false
+	for _, a := range event.Attendees {
+		if a.Self {
+			modify(a)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("not invited to event")
+	}
+	patch := &calendar.Event{Attendees: event.Attendees}
+	if _, err := svc.Events.Patch(calID, eventID, patch).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to patch event: %w", err)
+	}
+	return nil
+}
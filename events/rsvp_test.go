@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// newRSVPService serves an invitation to event abc and stores the patches of
+// it in *patched.
+func newRSVPService(t *testing.T, patched **calendar.Event) *calendar.Service {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/calendars/me@example.com/events/abc", r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(rw).Encode(&calendar.Event{Id: "abc", Attendees: []*calendar.EventAttendee{
+				{Email: "lead@example.com", ResponseStatus: "accepted", Organizer: true},
+				{Email: "me@example.com", ResponseStatus: "needsAction", Comment: "Joining remotely", Self: true},
+			}})
+		case http.MethodPatch:
+			*patched = new(calendar.Event)
+			if err := json.NewDecoder(r.Body).Decode(*patched); !assert.NoError(t, err) {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(rw).Encode(*patched)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	svc, err := calendar.NewService(context.Background(), option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	require.NoError(t, err)
+	return svc
+}
+
+func TestPatchResponse(t *testing.T) {
+	var patched *calendar.Event
+	n := newTestNotifier(t, newRSVPService(t, &patched), config.Calendar{ID: "me@example.com"})
+	n.EnableRSVP()
+	e := &Event{CalendarID: "me@example.com", ID: "abc", Summary: "Planning", Response: "needsAction"}
+	assert.Contains(t, n.reminderNotification(e, &Reminder{}, false).Actions, Action{Key: "rsvp:tentative", Label: "Tentative"})
+
+	require.NoError(t, n.patchResponse(context.Background(), "me@example.com", "abc", "tentative"))
+	require.NotNil(t, patched)
+	require.Len(t, patched.Attendees, 2)
+	assert.Equal(t, "accepted", patched.Attendees[0].ResponseStatus)
+	assert.Equal(t, "tentative", patched.Attendees[1].ResponseStatus)
+
+	e.Response = "tentative"
+	assert.NotContains(t, n.reminderNotification(e, &Reminder{}, false).Actions, Action{Key: "rsvp:tentative", Label: "Tentative"})
+}
+
+func TestRespondIsNotNotified(t *testing.T) {
+	var patched *calendar.Event
+	n := newTestNotifier(t, newRSVPService(t, &patched), config.Calendar{ID: "me@example.com"})
+	n.EnableRSVP()
+	sink := &memSink{}
+	n.AddSink(sink)
+
+	start := time.Now().Add(5 * time.Minute).Truncate(time.Minute)
+	planning := testEvent("abc", "Planning", start, 10)
+	planning.Attendees = []*calendar.EventAttendee{{Email: "me@example.com", ResponseStatus: "needsAction", Self: true}}
+	n.update(&n.cals[0], testEvents(planning), true, time.Time{})
+	n.checkReminders()
+	require.Len(t, sink.sent, 1)
+	id := sink.sent[0].ID
+
+	// accepting neither re-sends nor updates the reminder
+	n.OnAction(id, rsvpActionPrefix+"accepted")
+	require.Eventually(t, func() bool {
+		n.evMtx.Lock()
+		defer n.evMtx.Unlock()
+		return n.ev["me@example.com/abc"].Response == "accepted"
+	}, time.Second, time.Millisecond)
+	planning.Attendees[0].ResponseStatus = "accepted"
+	n.update(&n.cals[0], testEvents(planning), true, time.Time{})
+	assert.Len(t, sink.sent, 1)
+	assert.Empty(t, sink.updated)
+
+	// declining withdraws the reminder without telling about it
+	n.respond(n.ev["me@example.com/abc"], "declined")
+	assert.Equal(t, "declined", patched.Attendees[1].ResponseStatus)
+	assert.NotContains(t, n.ev, "me@example.com/abc")
+	assert.Equal(t, []uint32{id}, sink.closed)
+	planning.Attendees[0].ResponseStatus = "declined"
+	n.update(&n.cals[0], testEvents(planning), true, time.Time{})
+	assert.Len(t, sink.sent, 1)
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+)
+
+// state is the part of the notifier that is persisted across restarts, so
+// that reminders which were already delivered do not fire again.
+type state struct {
+	Events map[string]*Event // key: calendar ID + "/" + event ID
+}
+
+// loadState restores the event cache from config.Cfg.StatePath, dropping
+// events that have ended or belong to calendars that are no longer watched.
+func (n *Notifier) loadState() error {
+	data, err := os.ReadFile(config.Cfg.StatePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+	for id, e := range st.Events {
+		if e.End.Before(time.Now()) || !n.watching(e.CalendarID) {
+			n.dirty = true
+			continue
+		}
+		n.ev[id] = e
+		// avoid reusing the IDs of notifications that may still be shown
+		for _, r := range e.Reminders {
+			if r.NotificationID > n.lastID {
+				n.lastID = r.NotificationID
+			}
+		}
+		if e.EndReminderID > n.lastID {
+			n.lastID = e.EndReminderID
+		}
+	}
+	config.Debug.Printf("Loaded %d events from %s", len(n.ev), config.Cfg.StatePath)
+	return nil
+}
+
+// saveState persists the event cache if it has changed since the last call.
+// n.evMtx must be held.
+func (n *Notifier) saveState() {
+	if !n.dirty {
+		return
+	}
+	if err := writeState(&state{Events: n.ev}); err != nil {
+		log.Printf("Failed to write state: %v", err)
+		return
+	}
+	n.dirty = false
+}
+
+func writeState(st *state) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := os.MkdirAll(path.Dir(config.Cfg.StatePath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	// write to a temporary file first so that a crash leaves a valid state
+	tmp := config.Cfg.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, config.Cfg.StatePath)
+}
+
+func (n *Notifier) watching(calendarID string) bool {
+	for _, c := range n.cals {
+		if c.ID == calendarID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	n := newTestNotifier(t, nil, config.Calendar{ID: "me@example.com"})
+	upcoming := &Event{
+		CalendarID: "me@example.com",
+		ID:         "upcoming",
+		Summary:    "upcoming",
+		Start:      time.Now().Add(5 * time.Minute).Round(time.Second),
+		End:        time.Now().Add(time.Hour).Round(time.Second),
+		Reminders: []*Reminder{
+			{Before: 10 * time.Minute, Notified: true},
+			{Before: time.Minute},
+		},
+	}
+	n.ev["me@example.com/upcoming"] = upcoming
+	n.ev["me@example.com/ended"] = &Event{CalendarID: "me@example.com", End: time.Now().Add(-time.Minute)}
+	n.ev["other@example.com/upcoming"] = &Event{CalendarID: "other@example.com", End: time.Now().Add(time.Hour)}
+	n.dirty = true
+	n.saveState()
+	assert.False(t, n.dirty)
+
+	restored := &Notifier{cals: n.cals, ev: make(map[string]*Event)}
+	require.NoError(t, restored.loadState())
+	require.Len(t, restored.ev, 1)
+	e := restored.ev["me@example.com/upcoming"]
+	assert.True(t, e.Start.Equal(upcoming.Start))
+	require.Len(t, e.Reminders, 2)
+	assert.True(t, e.Reminders[0].Notified)
+	assert.False(t, e.Reminders[1].Notified)
+	assert.True(t, restored.dirty, "pruned events should be saved")
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// calendarSync holds the incremental synchronization state of a calendar.
+type calendarSync struct {
+	cal   *config.Calendar
+	token string    // next sync token, empty if a full sync is required
+	until time.Time // end of the time window covered by the last full sync
+}
+
+// sync fetches the changes of a calendar since the last sync. A full sync of
+// the time window [now, now+2*LookaheadInterval] is performed initially, when
+// the sync token has expired and when the window no longer covers the
+// lookahead interval, as incremental syncs only report changed events.
+func (n *Notifier) sync(ctx context.Context, s *calendarSync) error {
+	if s.token != "" && time.Now().Add(config.Cfg.LookaheadInterval.D).Before(s.until) {
+		events, err := listEvents(ctx, n.svc.Events.List(s.cal.ID).SyncToken(s.token))
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == http.StatusGone {
+			config.Debug.Printf("Sync token of %s expired, performing full sync", s.cal.ID)
+		} else if err != nil {
+			return err
+		} else {
+			n.update(s.cal, events, false, s.until)
+			s.token = events.NextSyncToken
+			return nil
+		}
+	}
+
+	timeMin := time.Now()
+	timeMax := timeMin.Add(2 * config.Cfg.LookaheadInterval.D)
+	events, err := listEvents(ctx, n.svc.Events.List(s.cal.ID).
+		TimeMin(timeMin.Format(time.RFC3339)).
+		TimeMax(timeMax.Format(time.RFC3339)))
+	if err != nil {
+		s.token = ""
+		return err
+	}
+	config.Debug.Printf("Full sync of %s: %d events", s.cal.ID, len(events.Items))
+	n.update(s.cal, events, true, timeMax)
+	s.token, s.until = events.NextSyncToken, timeMax
+	return nil
+}
+
+// List lists the events of cal that have not ended yet and start before until,
+// ordered by start. Like the events seen by a Notifier, it lacks cancelled and
+// declined events as well as those skipped by rules.
+func List(ctx context.Context, svc *calendar.Service, cal *config.Calendar, until time.Time) ([]*Event, error) {
+	events, err := listEvents(ctx, svc.Events.List(cal.ID).
+		TimeMin(time.Now().Format(time.RFC3339)).
+		TimeMax(until.Format(time.RFC3339)).
+		OrderBy("startTime"))
+	if err != nil {
+		return nil, err
+	}
+	loc := location(cal, events)
+	var list []*Event
+	for _, event := range events.Items {
+		if event.Status == "cancelled" || !attending(event) {
+			continue
+		}
+		e, res, err := newEvent(cal, events, event, loc)
+		if err != nil {
+			log.Printf("Failed to parse event %q: %v", event.Summary, err)
+			continue
+		}
+		if !res.Skip {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+// listEvents runs the list call and merges all result pages.
+func listEvents(ctx context.Context, call *calendar.EventsListCall) (*calendar.Events, error) {
+	if config.Cfg.PageSize > 0 {
+		call.MaxResults(config.Cfg.PageSize)
+	}
+	var events *calendar.Events
+	err := call.SingleEvents(true).Pages(ctx, func(page *calendar.Events) error {
+		items := page.Items
+		if events != nil {
+			items = append(events.Items, items...)
+		}
+		events = page
+		events.Items = items
+		return nil
+	})
+	return events, err
+}
@@ -0,0 +1,204 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// fakeCalendar serves the events list endpoint of the Calendar API. Full
+// syncs return the events in pages of pageSize; incremental syncs return
+// changes and answer 410 Gone for expired sync tokens.
+type fakeCalendar struct {
+	t        *testing.T
+	events   []*calendar.Event
+	changes  []*calendar.Event
+	expired  bool
+	requests []string // sync type of each request
+}
+
+func (f *fakeCalendar) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	assert.Equal(f.t, "/calendars/me@example.com/events", r.URL.Path)
+	assert.Equal(f.t, "true", q.Get("singleEvents"))
+
+	if q.Get("syncToken") != "" {
+		f.requests = append(f.requests, "incremental")
+		assert.Empty(f.t, q.Get("timeMin"))
+		if f.expired {
+			rw.WriteHeader(http.StatusGone)
+			fmt.Fprint(rw, `{"error":{"code":410,"message":"Sync token is no longer valid"}}`)
+			return
+		}
+		json.NewEncoder(rw).Encode(&calendar.Events{Items: f.changes, NextSyncToken: "next"})
+		return
+	}
+
+	f.requests = append(f.requests, "full")
+	assert.NotEmpty(f.t, q.Get("timeMin"))
+	assert.NotEmpty(f.t, q.Get("timeMax"))
+	pageSize, err := strconv.Atoi(q.Get("maxResults"))
+	if !assert.NoError(f.t, err) {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset := 0
+	if pt := q.Get("pageToken"); pt != "" {
+		if offset, err = strconv.Atoi(pt); !assert.NoError(f.t, err) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	page := &calendar.Events{
+		DefaultReminders: []*calendar.EventReminder{{Minutes: 10}},
+	}
+	if end := offset + pageSize; end < len(f.events) {
+		page.Items = f.events[offset:end]
+		page.NextPageToken = strconv.Itoa(end)
+	} else {
+		page.Items = f.events[offset:]
+		page.NextSyncToken = "initial"
+	}
+	json.NewEncoder(rw).Encode(page)
+}
+
+func newFakeCalendar(t *testing.T, n int) (*fakeCalendar, *calendar.Service) {
+	f := &fakeCalendar{t: t}
+	for i := 0; i < n; i++ {
+		f.events = append(f.events, &calendar.Event{
+			Id:        fmt.Sprintf("event%d", i),
+			Summary:   fmt.Sprintf("Event %d", i),
+			Start:     &calendar.EventDateTime{DateTime: "2030-05-12T12:00:00Z"},
+			End:       &calendar.EventDateTime{DateTime: "2030-05-12T12:30:00Z"},
+			Reminders: &calendar.EventReminders{UseDefault: true},
+		})
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	svc, err := calendar.NewService(context.Background(),
+		option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	return f, svc
+}
+
+func withPageSize(t *testing.T, size int64) {
+	prev := config.Cfg.PageSize
+	config.Cfg.PageSize = size
+	t.Cleanup(func() { config.Cfg.PageSize = prev })
+}
+
+func TestListEventsPages(t *testing.T) {
+	withPageSize(t, 2)
+	f, svc := newFakeCalendar(t, 5)
+
+	events, err := listEvents(context.Background(), svc.Events.List("me@example.com").
+		TimeMin("2030-05-12T00:00:00Z").TimeMax("2030-05-13T00:00:00Z"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"full", "full", "full"}, f.requests)
+	require.Len(t, events.Items, 5)
+	for i, e := range events.Items {
+		assert.Equal(t, fmt.Sprintf("event%d", i), e.Id)
+	}
+	assert.Equal(t, "initial", events.NextSyncToken)
+	assert.Len(t, events.DefaultReminders, 1)
+}
+
+func TestSync(t *testing.T) {
+	withPageSize(t, 3)
+	f, svc := newFakeCalendar(t, 4)
+	n := newTestNotifier(t, svc, config.Calendar{ID: "me@example.com"})
+	s := &calendarSync{cal: &n.cals[0]}
+
+	require.NoError(t, n.sync(context.Background(), s))
+	assert.Equal(t, []string{"full", "full"}, f.requests)
+	assert.Equal(t, "initial", s.token)
+	assert.Len(t, n.ev, 4)
+
+	f.requests = nil
+	f.changes = []*calendar.Event{{Id: "event1", Status: "cancelled"}}
+	require.NoError(t, n.sync(context.Background(), s))
+	assert.Equal(t, []string{"incremental"}, f.requests)
+	assert.Equal(t, "next", s.token)
+	assert.Len(t, n.ev, 3)
+	assert.NotContains(t, n.ev, "me@example.com/event1")
+
+	f.requests = nil
+	f.expired = true
+	require.NoError(t, n.sync(context.Background(), s))
+	assert.Equal(t, []string{"incremental", "full", "full"}, f.requests)
+	assert.Equal(t, "initial", s.token)
+	assert.Len(t, n.ev, 4)
+}
+
+func TestList(t *testing.T) {
+	withPageSize(t, 2)
+	f, svc := newFakeCalendar(t, 3)
+	f.events[0].Status = "cancelled"
+	f.events[1].Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: "declined"}}
+	f.events = append(f.events, &calendar.Event{
+		Id:    "broken",
+		Start: &calendar.EventDateTime{DateTime: "tomorrow"},
+		End:   &calendar.EventDateTime{DateTime: "tomorrow"},
+	}, &calendar.Event{
+		Id:    "trip",
+		Start: &calendar.EventDateTime{Date: "2030-05-14", TimeZone: "America/New_York"},
+		End:   &calendar.EventDateTime{Date: "2030-05-16", TimeZone: "America/New_York"},
+	})
+
+	list, err := List(context.Background(), svc, &config.Calendar{ID: "me@example.com"}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "event2", list[0].ID)
+	assert.Equal(t, []*Reminder{{Before: 10 * time.Minute}}, list[0].Reminders)
+	assert.True(t, list[1].AllDay)
+	assert.Equal(t, "2030-05-14T00:00:00-04:00", list[1].Start.Format(time.RFC3339))
+}
+
+func TestPollSyncsBeforeReminders(t *testing.T) {
+	withPageSize(t, 10)
+	_, svc := newFakeCalendar(t, 0)
+	n := newTestNotifier(t, svc, config.Calendar{ID: "me@example.com"})
+	sink := &memSink{}
+	n.AddSink(sink)
+
+	// a restored event that was deleted in the meantime
+	start := time.Now().Add(5 * time.Minute)
+	n.ev["me@example.com/deleted"] = &Event{
+		CalendarID: "me@example.com",
+		ID:         "deleted",
+		Summary:    "Standup",
+		Start:      start,
+		End:        start.Add(30 * time.Minute),
+		Reminders:  []*Reminder{{Before: 10 * time.Minute}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- n.Poll(ctx) }()
+	require.Eventually(t, func() bool {
+		n.evMtx.Lock()
+		defer n.evMtx.Unlock()
+		return len(n.ev) == 0
+	}, time.Second, time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// only the cancellation is announced, not the reminder
+	n.evMtx.Lock()
+	defer n.evMtx.Unlock()
+	require.Len(t, sink.sent, 1)
+	assert.Equal(t, "Cancelled: Standup", sink.sent[0].Summary)
+}
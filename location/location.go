@@ -9,7 +9,6 @@ import (
 	"github.com/svenschwermer/gcal-notify/config"
 	"github.com/svenschwermer/gcal-notify/slack"
 	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/googleapi"
 )
 
 type Slack interface {
@@ -44,16 +43,23 @@ func (b *Bot) Poll(ctx context.Context) error {
 
 		now := time.Now()
 		timeMax := now.Add(24 * time.Hour)
-		events, err := b.svc.Events.List(config.Cfg.CalendarID).Context(ctx).EventTypes("workingLocation").Do(
-			googleapi.QueryParameter("timeMin", now.Format(time.RFC3339)),
-			googleapi.QueryParameter("timeMax", timeMax.Format(time.RFC3339)),
-		)
+		call := b.svc.Events.List(b.calID).EventTypes("workingLocation").
+			TimeMin(now.Format(time.RFC3339)).
+			TimeMax(timeMax.Format(time.RFC3339))
+		if config.Cfg.PageSize > 0 {
+			call.MaxResults(config.Cfg.PageSize)
+		}
+		var items []*calendar.Event
+		err := call.Pages(ctx, func(page *calendar.Events) error {
+			items = append(items, page.Items...)
+			return nil
+		})
 		if err != nil {
 			log.Printf("Failed to query event list for location: %v", err)
 			continue
 		}
 
-		for _, event := range events.Items {
+		for _, event := range items {
 			start, err := time.Parse(time.DateOnly, event.Start.Date)
 			if err != nil {
 				log.Printf("Failed to parse start time for location: %v", err)
@@ -0,0 +1,229 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+// renewBefore is how long before its expiration a channel is renewed.
+const renewBefore = 10 * time.Minute
+
+// retryInterval is the delay before a failed watch request is retried.
+const retryInterval = time.Minute
+
+// Refresher is informed about changes pushed by Google Calendar.
+type Refresher interface {
+	// Refresh triggers an immediate sync of the calendars.
+	Refresh()
+	// SetPushActive reports whether all calendars are watched, i.e. whether
+	// polling may fall back to a longer interval.
+	SetPushActive(active bool)
+}
+
+type channel struct {
+	calID      string
+	id         string
+	resourceID string
+	expiration time.Time
+}
+
+type Watcher struct {
+	svc       *calendar.Service
+	cals      []config.Calendar
+	refresher Refresher
+	token     string
+
+	channels map[string]*channel // key: calendar ID
+	mtx      sync.Mutex
+}
+
+func NewWatcher(svc *calendar.Service, calendars []config.Calendar, refresher Refresher) (*Watcher, error) {
+	token, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		svc:       svc,
+		cals:      calendars,
+		refresher: refresher,
+		token:     token,
+		channels:  make(map[string]*channel),
+	}
+	return w, nil
+}
+
+// Run serves the push callbacks and keeps the watch channels alive until ctx
+// is done. If the receiver fails, push notifications are given up on and
+// polling continues at the regular interval.
+func (w *Watcher) Run(ctx context.Context) error {
+	srv := &http.Server{Addr: config.Cfg.Push.ListenAddress, Handler: w}
+	srvErr := make(chan error, 1)
+	go func() { srvErr <- srv.ListenAndServe() }()
+	defer w.stopAll()
+	defer srv.Close()
+
+	ticker := time.NewTimer(0)
+	for {
+		select {
+		case <-ticker.C:
+		case err := <-srvErr:
+			log.Printf("Push receiver failed, falling back to polling: %v", err)
+			w.refresher.SetPushActive(false)
+			return nil
+		case <-ctx.Done():
+			w.refresher.SetPushActive(false)
+			return ctx.Err()
+		}
+
+		next := time.Now().Add(config.Cfg.Push.ChannelTTL.D)
+		active := true
+		for _, cal := range w.cals {
+			w.mtx.Lock()
+			ch := w.channels[cal.ID]
+			w.mtx.Unlock()
+
+			renewAt := time.Time{}
+			if ch != nil {
+				renewAt = ch.expiration.Add(-renewBefore)
+			}
+			if time.Now().Before(renewAt) {
+				if renewAt.Before(next) {
+					next = renewAt
+				}
+				continue
+			}
+
+			newCh, err := w.watch(ctx, cal.ID)
+			if err != nil {
+				log.Printf("Failed to watch calendar %s: %v", cal.ID, err)
+				active = false
+				if retry := time.Now().Add(retryInterval); retry.Before(next) {
+					next = retry
+				}
+				continue
+			}
+			config.Debug.Printf("Watching calendar %s: channel=%s expiration=%v",
+				cal.ID, newCh.id, newCh.expiration)
+			w.mtx.Lock()
+			w.channels[cal.ID] = newCh
+			w.mtx.Unlock()
+			if ch != nil {
+				w.stop(ch)
+			}
+			if renewAt := newCh.expiration.Add(-renewBefore); renewAt.Before(next) {
+				next = renewAt
+			}
+		}
+		w.refresher.SetPushActive(active)
+		if active {
+			// catch up on changes that happened while no channel was active
+			w.refresher.Refresh()
+		}
+		ticker = time.NewTimer(time.Until(next))
+	}
+}
+
+func (w *Watcher) watch(ctx context.Context, calID string) (*channel, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	req := &calendar.Channel{
+		Id:      id,
+		Type:    "web_hook",
+		Address: config.Cfg.Push.URL,
+		Token:   w.token,
+		Params: map[string]string{
+			"ttl": strconv.FormatInt(int64(config.Cfg.Push.ChannelTTL.D/time.Second), 10),
+		},
+	}
+	resp, err := w.svc.Events.Watch(calID, req).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	ch := &channel{
+		calID:      calID,
+		id:         resp.Id,
+		resourceID: resp.ResourceId,
+		expiration: time.UnixMilli(resp.Expiration),
+	}
+	if resp.Expiration == 0 {
+		ch.expiration = time.Now().Add(config.Cfg.Push.ChannelTTL.D)
+	}
+	return ch, nil
+}
+
+func (w *Watcher) stop(ch *channel) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := w.svc.Channels.Stop(&calendar.Channel{Id: ch.id, ResourceId: ch.resourceID}).Context(ctx).Do()
+	if err != nil {
+		log.Printf("Failed to stop channel %s of calendar %s: %v", ch.id, ch.calID, err)
+	}
+}
+
+func (w *Watcher) stopAll() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	for id, ch := range w.channels {
+		w.stop(ch)
+		delete(w.channels, id)
+	}
+}
+
+// ServeHTTP handles the push callbacks of Google Calendar, see
+// https://developers.google.com/calendar/api/guides/push#receiving-notifications
+func (w *Watcher) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("X-Goog-Channel-Token") != w.token {
+		rw.WriteHeader(http.StatusForbidden)
+		return
+	}
+	chID := r.Header.Get("X-Goog-Channel-Id")
+	state := r.Header.Get("X-Goog-Resource-State")
+	if !w.known(chID) {
+		// Stale channel, e.g. from a previous run. Not acknowledging it
+		// would only make Google retry.
+		config.Debug.Printf("Push notification for unknown channel %s", chID)
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	config.Debug.Printf("Push notification: channel=%s state=%s", chID, state)
+	if state != "sync" {
+		w.refresher.Refresh()
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (w *Watcher) known(chID string) bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	for _, ch := range w.channels {
+		if ch.id == chID {
+			return true
+		}
+	}
+	return false
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
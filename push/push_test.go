@@ -0,0 +1,208 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// fakeRefresher records the calls of the Watcher.
+type fakeRefresher struct {
+	refreshes int
+	active    []bool
+	mtx       sync.Mutex
+}
+
+func (f *fakeRefresher) Refresh() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.refreshes++
+}
+
+func (f *fakeRefresher) SetPushActive(active bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.active = append(f.active, active)
+}
+
+// fakeCalendar serves the watch and stop endpoints of the Calendar API.
+// Channels expire after lifetime, or have no expiration if it is zero.
+type fakeCalendar struct {
+	t        *testing.T
+	lifetime time.Duration
+	watched  []string // channel IDs
+	stopped  []string
+	mtx      sync.Mutex
+}
+
+func (f *fakeCalendar) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var ch calendar.Channel
+	if err := json.NewDecoder(r.Body).Decode(&ch); !assert.NoError(f.t, err) {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	switch r.URL.Path {
+	case "/calendars/me@example.com/events/watch":
+		assert.Equal(f.t, "web_hook", ch.Type)
+		assert.Equal(f.t, "https://example.com/push", ch.Address)
+		assert.NotEmpty(f.t, ch.Token)
+		assert.Equal(f.t, "3600", ch.Params["ttl"])
+		f.watched = append(f.watched, ch.Id)
+		resp := &calendar.Channel{Id: ch.Id, ResourceId: "resource"}
+		if f.lifetime != 0 {
+			resp.Expiration = time.Now().Add(f.lifetime).UnixMilli()
+		}
+		json.NewEncoder(rw).Encode(resp)
+	case "/channels/stop":
+		assert.Equal(f.t, "resource", ch.ResourceId)
+		f.stopped = append(f.stopped, ch.Id)
+	default:
+		f.t.Errorf("unexpected request %s", r.URL.Path)
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCalendar) calls() (watched, stopped []string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]string(nil), f.watched...), append([]string(nil), f.stopped...)
+}
+
+func newFakeCalendar(t *testing.T, lifetime time.Duration) (*fakeCalendar, *calendar.Service) {
+	f := &fakeCalendar{t: t, lifetime: lifetime}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	svc, err := calendar.NewService(context.Background(),
+		option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	return f, svc
+}
+
+func withPushConfig(t *testing.T) {
+	prev := config.Cfg.Push
+	config.Cfg.Push = config.Push{
+		URL:           "https://example.com/push",
+		ListenAddress: "127.0.0.1:0",
+		ChannelTTL:    config.Duration{D: time.Hour},
+		PollInterval:  config.Duration{D: time.Hour},
+	}
+	t.Cleanup(func() { config.Cfg.Push = prev })
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := &fakeRefresher{}
+	w, err := NewWatcher(nil, []config.Calendar{{ID: "me@example.com"}}, r)
+	require.NoError(t, err)
+	w.channels["me@example.com"] = &channel{calID: "me@example.com", id: "ch1"}
+
+	tests := []struct {
+		name      string
+		method    string
+		token     string
+		channel   string
+		state     string
+		status    int
+		refreshes int
+	}{
+		{"wrong method", http.MethodGet, w.token, "ch1", "exists", http.StatusMethodNotAllowed, 0},
+		{"wrong token", http.MethodPost, "guess", "ch1", "exists", http.StatusForbidden, 0},
+		{"unknown channel", http.MethodPost, w.token, "stale", "exists", http.StatusOK, 0},
+		{"sync", http.MethodPost, w.token, "ch1", "sync", http.StatusOK, 0},
+		{"change", http.MethodPost, w.token, "ch1", "exists", http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.refreshes = 0
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("X-Goog-Channel-Token", tt.token)
+			req.Header.Set("X-Goog-Channel-Id", tt.channel)
+			req.Header.Set("X-Goog-Resource-State", tt.state)
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.refreshes, r.refreshes)
+		})
+	}
+}
+
+func TestRunRenewsChannels(t *testing.T) {
+	withPushConfig(t)
+	f, svc := newFakeCalendar(t, renewBefore+200*time.Millisecond)
+	r := &fakeRefresher{}
+	w, err := NewWatcher(svc, []config.Calendar{{ID: "me@example.com"}}, r)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	// the channel is renewed shortly before it expires, and only then the
+	// previous one is stopped
+	require.Eventually(t, func() bool {
+		watched, stopped := f.calls()
+		return len(watched) >= 2 && len(stopped) >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+	watched, stopped := f.calls()
+	assert.Equal(t, watched[0], stopped[0])
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// all channels are stopped on exit
+	watched, stopped = f.calls()
+	assert.Equal(t, watched, stopped)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	assert.GreaterOrEqual(t, r.refreshes, 2)
+	assert.True(t, r.active[0])
+	assert.False(t, r.active[len(r.active)-1])
+}
+
+func TestRunReceiverFails(t *testing.T) {
+	withPushConfig(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	config.Cfg.Push.ListenAddress = l.Addr().String()
+
+	f, svc := newFakeCalendar(t, time.Hour)
+	r := &fakeRefresher{}
+	w, err := NewWatcher(svc, []config.Calendar{{ID: "me@example.com"}}, r)
+	require.NoError(t, err)
+
+	// the address is taken, which does not stop the daemon
+	assert.NoError(t, w.Run(context.Background()))
+	watched, stopped := f.calls()
+	assert.Equal(t, watched, stopped)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	assert.False(t, r.active[len(r.active)-1])
+}
+
+func TestWatchWithoutExpiration(t *testing.T) {
+	withPushConfig(t)
+	_, svc := newFakeCalendar(t, 0)
+	w, err := NewWatcher(svc, nil, &fakeRefresher{})
+	require.NoError(t, err)
+
+	// the requested lifetime is assumed
+	ch, err := w.watch(context.Background(), "me@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "resource", ch.resourceID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), ch.expiration, time.Second)
+}
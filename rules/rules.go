@@ -0,0 +1,102 @@
+// Package rules filters and transforms events according to the configured
+// rules.
+package rules
+
+import (
+	"strings"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+// Result is the combined outcome of all rules matching an event.
+type Result struct {
+	Skip bool
+	// Reminders replace the event's reminders if ReplaceReminders is set and
+	// are added to them otherwise.
+	Reminders        []time.Duration
+	ReplaceReminders bool
+	Urgency          string // empty if unchanged
+	Prefix           string
+	Matched          []string // names of the matching rules
+}
+
+// Evaluate applies the rules to an event of the calendar calID.
+func Evaluate(rules []config.Rule, calID string, e *calendar.Event) Result {
+	var res Result
+	for i := range rules {
+		r := &rules[i]
+		if !Match(r, calID, e) {
+			continue
+		}
+		res.Matched = append(res.Matched, r.Name)
+		if r.Skip {
+			res.Skip = true
+			return res
+		}
+		if r.Reminders != nil && !r.MergeReminders {
+			res.Reminders = []time.Duration{}
+			res.ReplaceReminders = true
+		}
+		for _, d := range r.Reminders {
+			res.Reminders = append(res.Reminders, d.D)
+		}
+		if r.Urgency != "" {
+			res.Urgency = r.Urgency
+		}
+		res.Prefix += r.Prefix
+	}
+	return res
+}
+
+// Match returns whether all conditions of r match an event of the calendar
+// calID.
+func Match(r *config.Rule, calID string, e *calendar.Event) bool {
+	if r.Summary.Regexp != nil && !r.Summary.MatchString(e.Summary) {
+		return false
+	}
+	if r.Organizer != "" && (e.Organizer == nil || !strings.EqualFold(e.Organizer.Email, r.Organizer)) {
+		return false
+	}
+	if r.Calendar != "" && r.Calendar != calID {
+		return false
+	}
+	if r.MinAttendees > 0 && len(e.Attendees) < r.MinAttendees {
+		return false
+	}
+	if r.MaxAttendees > 0 && len(e.Attendees) > r.MaxAttendees {
+		return false
+	}
+	if r.EventType != "" && r.EventType != withDefault(e.EventType, "default") {
+		return false
+	}
+	if r.ColorID != "" && r.ColorID != e.ColorId {
+		return false
+	}
+	if r.Transparency != "" && r.Transparency != withDefault(e.Transparency, "opaque") {
+		return false
+	}
+	if r.Response != "" && r.Response != Response(e) {
+		return false
+	}
+	return true
+}
+
+// Response returns the own response status to an event. Events without
+// attendees only exist in one's own calendar and count as accepted.
+func Response(e *calendar.Event) string {
+	for _, a := range e.Attendees {
+		if a.Self {
+			return a.ResponseStatus
+		}
+	}
+	return "accepted"
+}
+
+func withDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
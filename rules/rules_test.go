@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/svenschwermer/gcal-notify/config"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestMatch(t *testing.T) {
+	standup := &calendar.Event{
+		Summary:   "Daily Standup",
+		Organizer: &calendar.EventOrganizer{Email: "Lead@example.com"},
+		Attendees: []*calendar.EventAttendee{
+			{Email: "lead@example.com", ResponseStatus: "accepted"},
+			{Email: "me@example.com", Self: true, ResponseStatus: "tentative"},
+			{Email: "dev@example.com", ResponseStatus: "needsAction"},
+		},
+		ColorId: "5",
+	}
+	focus := &calendar.Event{Summary: "Focus time", EventType: "focusTime", Transparency: "transparent"}
+
+	tests := []struct {
+		name  string
+		rule  config.Rule
+		event *calendar.Event
+		want  bool
+	}{
+		{name: "empty rule", event: standup, want: true},
+		{name: "summary", rule: config.Rule{Summary: config.Regexp{Regexp: regexp.MustCompile(`(?i)standup`)}}, event: standup, want: true},
+		{name: "summary mismatch", rule: config.Rule{Summary: config.Regexp{Regexp: regexp.MustCompile(`^Standup`)}}, event: standup},
+		{name: "organizer", rule: config.Rule{Organizer: "lead@example.com"}, event: standup, want: true},
+		{name: "no organizer", rule: config.Rule{Organizer: "lead@example.com"}, event: focus},
+		{name: "calendar", rule: config.Rule{Calendar: "me@example.com"}, event: standup, want: true},
+		{name: "other calendar", rule: config.Rule{Calendar: "team@example.com"}, event: standup},
+		{name: "min attendees", rule: config.Rule{MinAttendees: 3}, event: standup, want: true},
+		{name: "too few attendees", rule: config.Rule{MinAttendees: 4}, event: standup},
+		{name: "too many attendees", rule: config.Rule{MaxAttendees: 2}, event: standup},
+		{name: "event type", rule: config.Rule{EventType: "focusTime"}, event: focus, want: true},
+		{name: "default event type", rule: config.Rule{EventType: "default"}, event: standup, want: true},
+		{name: "color", rule: config.Rule{ColorID: "5"}, event: standup, want: true},
+		{name: "color mismatch", rule: config.Rule{ColorID: "5"}, event: focus},
+		{name: "free", rule: config.Rule{Transparency: "transparent"}, event: focus, want: true},
+		{name: "busy by default", rule: config.Rule{Transparency: "opaque"}, event: standup, want: true},
+		{name: "response", rule: config.Rule{Response: "tentative"}, event: standup, want: true},
+		{name: "own event accepted", rule: config.Rule{Response: "needsAction"}, event: focus},
+		{
+			name:  "all conditions",
+			rule:  config.Rule{Organizer: "lead@example.com", MinAttendees: 2, Response: "tentative", ColorID: "5"},
+			event: standup,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(&tt.rule, "me@example.com", tt.event))
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	event := &calendar.Event{
+		Summary:   "Company all-hands",
+		Attendees: make([]*calendar.EventAttendee, 50),
+	}
+	large := config.Rule{Name: "large", MinAttendees: 20, Urgency: "low", Prefix: "[FYI] ",
+		Reminders: []config.Duration{{D: 15 * time.Minute}}}
+	allHands := config.Rule{Name: "all-hands", Summary: config.Regexp{Regexp: regexp.MustCompile(`all-hands`)},
+		Urgency: "critical", Prefix: "📣 "}
+	skip := config.Rule{Name: "skip", MinAttendees: 100, Skip: true}
+	ping := config.Rule{Name: "ping", Reminders: []config.Duration{{D: time.Minute}}, MergeReminders: true}
+	skipAll := config.Rule{Name: "skip all", Skip: true}
+
+	tests := []struct {
+		name  string
+		rules []config.Rule
+		want  Result
+	}{
+		{name: "no rules"},
+		{
+			name:  "later rules override",
+			rules: []config.Rule{large, skip, allHands},
+			want: Result{
+				Reminders:        []time.Duration{15 * time.Minute},
+				ReplaceReminders: true,
+				Urgency:          "critical",
+				Prefix:           "[FYI] 📣 ",
+				Matched:          []string{"large", "all-hands"},
+			},
+		},
+		{
+			name:  "merge reminders",
+			rules: []config.Rule{ping, large, ping},
+			want: Result{
+				Reminders:        []time.Duration{15 * time.Minute, time.Minute},
+				ReplaceReminders: true,
+				Urgency:          "low",
+				Prefix:           "[FYI] ",
+				Matched:          []string{"ping", "large", "ping"},
+			},
+		},
+		{
+			name:  "merge only",
+			rules: []config.Rule{ping},
+			want:  Result{Reminders: []time.Duration{time.Minute}, Matched: []string{"ping"}},
+		},
+		{
+			name:  "skip",
+			rules: []config.Rule{large, skipAll, allHands},
+			want: Result{
+				Skip:             true,
+				Reminders:        []time.Duration{15 * time.Minute},
+				ReplaceReminders: true,
+				Urgency:          "low",
+				Prefix:           "[FYI] ",
+				Matched:          []string{"large", "skip all"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Evaluate(tt.rules, "me@example.com", event))
+		})
+	}
+}
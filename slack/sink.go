@@ -0,0 +1,213 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+// Block Kit, see https://api.slack.com/reference/block-kit/blocks
+type block struct {
+	Type     string  `json:"type"`
+	Text     *text   `json:"text,omitempty"`
+	Elements []*text `json:"elements,omitempty"`
+}
+
+type text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(s string) *text {
+	return &text{Type: "mrkdwn", Text: s}
+}
+
+// maxSectionText is the maximum length of the text of a section block.
+const maxSectionText = 3000
+
+// Sink posts reminders as direct messages to the token's owner, so they reach
+// the phone when away from the desktop. Messages are posted asynchronously in
+// the order the notifications are delivered.
+type Sink struct {
+	c     *Client
+	queue chan func(context.Context)
+
+	channel string            // direct message channel with oneself
+	ts      map[uint32]string // key: notification ID, value: message timestamp
+	mtx     sync.Mutex
+}
+
+func NewSink(c *Client) *Sink {
+	return &Sink{
+		c:     c,
+		queue: make(chan func(context.Context), 64),
+		ts:    make(map[uint32]string),
+	}
+}
+
+// Run posts the queued messages until ctx is done.
+func (s *Sink) Run(ctx context.Context) error {
+	for {
+		select {
+		case f := <-s.queue:
+			reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			f(reqCtx)
+			cancel()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Sink) enqueue(f func(context.Context)) error {
+	select {
+	case s.queue <- f:
+		return nil
+	default:
+		return fmt.Errorf("slack queue full")
+	}
+}
+
+func (s *Sink) Send(n *events.Notification) error {
+	msg := reminderMessage(n)
+	id := n.ID
+	return s.enqueue(func(ctx context.Context) {
+		if err := s.post(ctx, id, msg); err != nil {
+			log.Printf("Failed to post Slack reminder %q: %v", msg.Text, err)
+		}
+	})
+}
+
+func (s *Sink) Update(n *events.Notification) error {
+	msg := reminderMessage(n)
+	id := n.ID
+	return s.enqueue(func(ctx context.Context) {
+		s.mtx.Lock()
+		msg.TS = s.ts[id]
+		s.mtx.Unlock()
+		if err := s.post(ctx, id, msg); err != nil {
+			log.Printf("Failed to update Slack reminder %q: %v", msg.Text, err)
+		}
+	})
+}
+
+// Close keeps the message, as it serves as a record on other devices.
+func (s *Sink) Close(id uint32) error {
+	return nil
+}
+
+func (s *Sink) post(ctx context.Context, id uint32, msg *message) error {
+	channel, err := s.selfDM(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open direct message channel: %w", err)
+	}
+	msg.Channel = channel
+	ts, err := s.c.postMessage(ctx, msg)
+	if err != nil {
+		return err
+	}
+	config.Debug.Printf("slack: notification %d posted as %s", id, ts)
+	s.mtx.Lock()
+	s.ts[id] = ts
+	s.mtx.Unlock()
+	return nil
+}
+
+func (s *Sink) selfDM(ctx context.Context) (string, error) {
+	s.mtx.Lock()
+	channel := s.channel
+	s.mtx.Unlock()
+	if channel != "" {
+		return channel, nil
+	}
+
+	self, err := s.c.self(ctx)
+	if err != nil {
+		return "", err
+	}
+	channel, err = s.c.openDM(ctx, self)
+	if err != nil {
+		return "", err
+	}
+	s.mtx.Lock()
+	s.channel = channel
+	s.mtx.Unlock()
+	return channel, nil
+}
+
+// reminderMessage renders a notification with start time, join link and
+// agenda.
+func reminderMessage(n *events.Notification) *message {
+	e := n.Event
+	msg := &message{Text: n.Summary}
+
+	when := fmt.Sprintf("%s – %s", e.Start.Format("Mon 2 Jan 15:04"), e.End.Format("15:04"))
+	if e.AllDay {
+		when = e.Start.Format("Mon 2 Jan") + " (all day)"
+	}
+	lines := []string{"*" + escape(n.Summary) + "*", ":clock3: " + when}
+	if e.Conference.URL != "" {
+		provider := e.Conference.Provider
+		if provider == "" {
+			provider = "meeting"
+		}
+		lines = append(lines, fmt.Sprintf(":movie_camera: <%s|Join %s>", e.Conference.URL, escape(provider)))
+	}
+	if e.Location != "" && e.Location != e.Conference.URL {
+		lines = append(lines, ":round_pushpin: "+escape(e.Location))
+	}
+	if e.Link != "" {
+		lines = append(lines, fmt.Sprintf(":calendar: <%s|Open in Calendar>", e.Link))
+	}
+	msg.Blocks = append(msg.Blocks, block{Type: "section", Text: mrkdwn(strings.Join(lines, "\n"))})
+
+	if agenda := plainText(n.Body); agenda != "" {
+		msg.Blocks = append(msg.Blocks,
+			block{Type: "divider"},
+			block{Type: "section", Text: mrkdwn(agenda)})
+	}
+	return msg
+}
+
+var (
+	lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>`)
+	htmlTags      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// plainText converts an event description, which may be HTML, to escaped
+// plain text fitting into a section block.
+func plainText(s string) string {
+	s = lineBreakTags.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = strings.TrimSpace(html.UnescapeString(s))
+	return escape(truncate(s, maxSectionText))
+}
+
+// truncate shortens s so that it has at most n characters once escaped.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(escape(s)) <= n {
+		return s
+	}
+	length := 0
+	for i, r := range s {
+		length += utf8.RuneCountInString(escape(string(r)))
+		if length > n-3 {
+			return s[:i] + "..."
+		}
+	}
+	return s
+}
+
+// escape escapes the control characters of Slack's mrkdwn.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -5,12 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
 )
 
 type WorkingLocation int
@@ -21,7 +25,11 @@ const (
 )
 
 type Client struct {
-	token string
+	token   string
+	baseURL string
+
+	selfID string // user ID of the token's owner, see self
+	mtx    sync.Mutex
 }
 
 func NewClient() (*Client, error) {
@@ -32,7 +40,8 @@ func NewClient() (*Client, error) {
 	token = bytes.TrimSpace(token)
 
 	c := &Client{
-		token: string(token),
+		token:   string(token),
+		baseURL: config.Cfg.SlackBaseURL,
 	}
 
 	return c, nil
@@ -61,16 +70,111 @@ func (c *Client) SetWorkingLocation(ctx context.Context, loc WorkingLocation) er
 		return fmt.Errorf("unexpected working location: %v", loc)
 	}
 
+	return c.call(ctx, "users.profile.set", body, nil)
+}
+
+// self returns the user ID of the token's owner.
+func (c *Client) self(ctx context.Context) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.selfID != "" {
+		return c.selfID, nil
+	}
+
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.call(ctx, "auth.test", struct{}{}, &resp); err != nil {
+		return "", err
+	}
+	c.selfID = resp.UserID
+	return c.selfID, nil
+}
+
+// openDM opens the direct message conversation with the given users and
+// returns its channel ID.
+func (c *Client) openDM(ctx context.Context, users string) (string, error) {
+	body := struct {
+		Users string `json:"users"`
+	}{users}
+	var resp struct {
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+	}
+	if err := c.call(ctx, "conversations.open", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Channel.ID, nil
+}
+
+// lookupByEmail returns the user ID of the user with the given e-mail address.
+func (c *Client) lookupByEmail(ctx context.Context, email string) (string, error) {
+	var resp struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if err := c.call(ctx, "users.lookupByEmail", url.Values{"email": {email}}, &resp); err != nil {
+		return "", err
+	}
+	return resp.User.ID, nil
+}
+
+// MessageOrganizer sends text as direct message to the organizer of e.
+func (c *Client) MessageOrganizer(ctx context.Context, e *events.Event, text string) error {
+	user, err := c.lookupByEmail(ctx, e.Organizer)
+	if err != nil {
+		return fmt.Errorf("failed to look up organizer: %w", err)
+	}
+	channel, err := c.openDM(ctx, user)
+	if err != nil {
+		return fmt.Errorf("failed to open direct message channel: %w", err)
+	}
+	_, err = c.postMessage(ctx, &message{Channel: channel, Text: text})
+	return err
+}
+
+type message struct {
+	Channel string  `json:"channel"`
+	TS      string  `json:"ts,omitempty"`
+	Text    string  `json:"text"`
+	Blocks  []block `json:"blocks,omitempty"`
+}
+
+// postMessage posts a message, or updates it if msg.TS is set, and returns its
+// timestamp, which identifies it within the channel.
+func (c *Client) postMessage(ctx context.Context, msg *message) (string, error) {
+	method := "chat.postMessage"
+	if msg.TS != "" {
+		method = "chat.update"
+	}
+	var resp struct {
+		TS string `json:"ts"`
+	}
+	if err := c.call(ctx, method, msg, &resp); err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+// call invokes a Web API method with a JSON body, or a form for methods not
+// accepting JSON, and decodes the response into result, if not nil.
+func (c *Client) call(ctx context.Context, method string, body, result any) error {
 	bodyBuf := new(bytes.Buffer)
-	if err := json.NewEncoder(bodyBuf).Encode(body); err != nil {
+	contentType := "application/json; charset=utf-8"
+	if form, ok := body.(url.Values); ok {
+		bodyBuf.WriteString(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else if err := json.NewEncoder(bodyBuf).Encode(body); err != nil {
 		return fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, "https://slack.com/api/users.profile.set", bodyBuf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bodyBuf)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", "Bearer "+c.token)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -80,24 +184,33 @@ func (c *Client) SetWorkingLocation(ctx context.Context, loc WorkingLocation) er
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status %s", resp.Status)
+		return fmt.Errorf("%s request failed with status %s", method, resp.Status)
 	}
 
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
 	var respBody struct {
 		OK      bool   `json:"ok"`
 		Error   string `json:"error"`
 		Warning string `json:"warning"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+	if err := json.Unmarshal(respBytes, &respBody); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if !respBody.OK {
-		return fmt.Errorf("request failed: %s", respBody.Error)
+		return fmt.Errorf("%s request failed: %s", method, respBody.Error)
 	}
 	if respBody.Warning != "" {
 		log.Printf("Slack warning: %s", respBody.Warning)
 	}
 
+	if result != nil {
+		if err := json.Unmarshal(respBytes, result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/conference"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+// fakeSlack serves the Web API methods used by the client.
+type fakeSlack struct {
+	t        *testing.T
+	calls    []string
+	messages []map[string]any
+}
+
+func (f *fakeSlack) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	f.calls = append(f.calls, method)
+	assert.Equal(f.t, "Bearer xoxp-test", r.Header.Get("Authorization"))
+
+	var body map[string]any
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); !assert.NoError(f.t, err) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = make(map[string]any)
+		for k := range r.PostForm {
+			body[k] = r.PostForm.Get(k)
+		}
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&body); !assert.NoError(f.t, err) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var resp string
+	switch method {
+	case "auth.test":
+		resp = `{"ok":true,"user_id":"U123"}`
+	case "conversations.open":
+		switch body["users"] {
+		case "U123":
+			resp = `{"ok":true,"channel":{"id":"D456"}}`
+		case "U789":
+			resp = `{"ok":true,"channel":{"id":"D789"}}`
+		default:
+			f.t.Errorf("unexpected users %v", body["users"])
+		}
+	case "users.lookupByEmail":
+		if body["email"] == "lead@example.com" {
+			resp = `{"ok":true,"user":{"id":"U789"}}`
+		} else {
+			resp = `{"ok":false,"error":"users_not_found"}`
+		}
+	case "chat.postMessage":
+		f.messages = append(f.messages, body)
+		resp = `{"ok":true,"channel":"D456","ts":"1700000000.000100"}`
+	case "chat.update":
+		assert.Equal(f.t, "1700000000.000100", body["ts"])
+		f.messages = append(f.messages, body)
+		resp = `{"ok":true,"channel":"D456","ts":"1700000000.000100"}`
+	case "users.profile.set":
+		resp = `{"ok":true,"warning":"superfluous_charset"}`
+	default:
+		resp = `{"ok":false,"error":"unknown_method"}`
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write([]byte(resp))
+}
+
+func newFakeSlack(t *testing.T) (*fakeSlack, *Client) {
+	f := &fakeSlack{t: t}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &Client{token: "xoxp-test", baseURL: srv.URL + "/api/"}
+}
+
+func TestSinkPostsDirectMessage(t *testing.T) {
+	f, c := newFakeSlack(t)
+	s := NewSink(c)
+	start := time.Date(2030, 5, 12, 14, 0, 0, 0, time.UTC)
+	n := &events.Notification{
+		ID: 1,
+		Event: &events.Event{
+			Summary:    "Planning <Q3>",
+			Start:      start,
+			End:        start.Add(time.Hour),
+			Conference: conference.Link{URL: "https://zoom.us/j/5551234567", Provider: "Zoom"},
+			Link:       "https://www.google.com/calendar/event?eid=abc",
+		},
+		Summary: "14:00 | Planning <Q3>",
+		Body:    "<b>Agenda</b><br>1. Roadmap &amp; budget<br>2. Hiring",
+	}
+
+	ctx := context.Background()
+	require.NoError(t, s.Send(n))
+	(<-s.queue)(ctx)
+	require.NoError(t, s.Update(n))
+	(<-s.queue)(ctx)
+
+	assert.Equal(t, []string{"auth.test", "conversations.open", "chat.postMessage", "chat.update"}, f.calls)
+	require.Len(t, f.messages, 2)
+	msg := f.messages[0]
+	assert.Equal(t, "D456", msg["channel"])
+	assert.Equal(t, "14:00 | Planning <Q3>", msg["text"])
+
+	blocks := msg["blocks"].([]any)
+	require.Len(t, blocks, 3)
+	section := blocks[0].(map[string]any)["text"].(map[string]any)["text"].(string)
+	assert.Contains(t, section, "*14:00 | Planning &lt;Q3&gt;*")
+	assert.Contains(t, section, "Sun 12 May 14:00 – 15:00")
+	assert.Contains(t, section, "<https://zoom.us/j/5551234567|Join Zoom>")
+	agenda := blocks[2].(map[string]any)["text"].(map[string]any)["text"].(string)
+	assert.Equal(t, "Agenda\n1. Roadmap &amp; budget\n2. Hiring", agenda)
+}
+
+func TestTruncateAgenda(t *testing.T) {
+	n := &events.Notification{
+		Event: &events.Event{Summary: "Retro"},
+		Body:  strings.Repeat("ä", maxSectionText-4) + "&b",
+	}
+	blocks := reminderMessage(n).Blocks
+	require.Len(t, blocks, 3)
+	agenda := blocks[2].Text.Text
+	assert.True(t, utf8.ValidString(agenda))
+	assert.Equal(t, strings.Repeat("ä", maxSectionText-4)+"...", agenda)
+
+	n.Body = strings.Repeat("&", maxSectionText)
+	agenda = reminderMessage(n).Blocks[2].Text.Text
+	assert.Equal(t, strings.Repeat("&amp;", (maxSectionText-3)/5)+"...", agenda)
+}
+
+func TestMessageOrganizer(t *testing.T) {
+	f, c := newFakeSlack(t)
+	e := &events.Event{Summary: "Planning", Organizer: "lead@example.com"}
+	require.NoError(t, c.MessageOrganizer(context.Background(), e, "Running 5 min late"))
+	assert.Equal(t, []string{"users.lookupByEmail", "conversations.open", "chat.postMessage"}, f.calls)
+	require.Len(t, f.messages, 1)
+	assert.Equal(t, "D789", f.messages[0]["channel"])
+	assert.Equal(t, "Running 5 min late", f.messages[0]["text"])
+
+	e.Organizer = "nobody@example.com"
+	err := c.MessageOrganizer(context.Background(), e, "Running 5 min late")
+	assert.EqualError(t, err, "failed to look up organizer: users.lookupByEmail request failed: users_not_found")
+}
+
+func TestSetWorkingLocation(t *testing.T) {
+	f, c := newFakeSlack(t)
+	require.NoError(t, c.SetWorkingLocation(context.Background(), WorkingLocationHome))
+	assert.Equal(t, []string{"users.profile.set"}, f.calls)
+	assert.Error(t, c.SetWorkingLocation(context.Background(), WorkingLocation(42)))
+}
+
+func TestCallError(t *testing.T) {
+	_, c := newFakeSlack(t)
+	err := c.call(context.Background(), "chat.delete", struct{}{}, nil)
+	assert.EqualError(t, err, "chat.delete request failed: unknown_method")
+}
@@ -0,0 +1,124 @@
+// Package terminal delivers notifications as text to a terminal, for headless
+// sessions without notification daemon.
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+// Escape sequences for terminal-native notifications.
+const (
+	EscapeOSC9   = "osc9"   // iTerm2, Windows Terminal, ConEmu, foot, kitty
+	EscapeOSC777 = "osc777" // urxvt, foot, VTE-based terminals
+)
+
+type Sink struct {
+	out    io.Writer
+	bell   bool
+	escape string
+	tmux   bool
+	mtx    sync.Mutex
+}
+
+// New returns a sink writing to config.Cfg.Terminal.Output, or to stdout if
+// not set.
+func New() (*Sink, error) {
+	cfg := config.Cfg.Terminal
+	s := &Sink{
+		out:    os.Stdout,
+		bell:   cfg.Bell,
+		escape: cfg.Escape,
+		tmux:   os.Getenv("TMUX") != "",
+	}
+	if cfg.Output != "" {
+		f, err := os.OpenFile(cfg.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open terminal output: %w", err)
+		}
+		s.out = f
+	}
+	switch s.escape {
+	case "", EscapeOSC9, EscapeOSC777:
+	default:
+		return nil, fmt.Errorf("unsupported terminal escape sequence %q", s.escape)
+	}
+	return s, nil
+}
+
+func (s *Sink) Send(n *events.Notification) error {
+	return s.write(n, "")
+}
+
+func (s *Sink) Update(n *events.Notification) error {
+	return s.write(n, " (updated)")
+}
+
+func (s *Sink) Close(id uint32) error {
+	return nil
+}
+
+func (s *Sink) write(n *events.Notification, suffix string) error {
+	var b strings.Builder
+	if s.bell {
+		b.WriteString("\a")
+	}
+	switch s.escape {
+	case EscapeOSC9:
+		b.WriteString(s.osc("9;" + sanitize(n.Summary)))
+	case EscapeOSC777:
+		b.WriteString(s.osc("777;notify;" + sanitize(n.Summary) + ";" + sanitize(firstLine(n.Body))))
+	}
+
+	fmt.Fprintf(&b, "%s %s%s\n", time.Now().Format("15:04:05"), n.Summary, suffix)
+	indent := strings.Repeat(" ", len("15:04:05 "))
+	if e := n.Event; e != nil {
+		if e.Conference.URL != "" {
+			fmt.Fprintf(&b, "%sJoin: %s\n", indent, e.Conference.URL)
+		}
+		if e.Location != "" && e.Location != e.Conference.URL {
+			fmt.Fprintf(&b, "%sLocation: %s\n", indent, e.Location)
+		}
+		if e.Link != "" {
+			fmt.Fprintf(&b, "%sEvent: %s\n", indent, e.Link)
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, err := io.WriteString(s.out, b.String())
+	return err
+}
+
+// osc returns an operating system command sequence, wrapped for pass-through
+// when running inside tmux.
+func (s *Sink) osc(cmd string) string {
+	seq := "\x1b]" + cmd + "\a"
+	if s.tmux {
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// sanitize removes characters which would terminate an escape sequence or
+// separate its fields.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == ';' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
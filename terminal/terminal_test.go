@@ -0,0 +1,83 @@
+package terminal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/conference"
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+func TestWrite(t *testing.T) {
+	n := &events.Notification{
+		Summary: "14:00 | Standup; daily",
+		Body:    "Agenda\nmore",
+		Event: &events.Event{
+			Conference: conference.Link{URL: "https://meet.google.com/abc-defg-hij"},
+			Location:   "Room 1",
+		},
+	}
+	// strip the time stamp
+	ts := regexp.MustCompile(`\d\d:\d\d:\d\d `)
+
+	tests := []struct {
+		name string
+		sink *Sink
+		want string
+	}{
+		{
+			name: "plain",
+			sink: &Sink{},
+			want: "14:00 | Standup; daily\n" +
+				"         Join: https://meet.google.com/abc-defg-hij\n" +
+				"         Location: Room 1\n",
+		},
+		{
+			name: "bell and osc9",
+			sink: &Sink{bell: true, escape: EscapeOSC9},
+			want: "\a\x1b]9;14:00 | Standup  daily\a14:00 | Standup; daily\n" +
+				"         Join: https://meet.google.com/abc-defg-hij\n" +
+				"         Location: Room 1\n",
+		},
+		{
+			name: "osc777 in tmux",
+			sink: &Sink{escape: EscapeOSC777, tmux: true},
+			want: "\x1bPtmux;\x1b\x1b]777;notify;14:00 | Standup  daily;Agenda\a\x1b\\14:00 | Standup; daily\n" +
+				"         Join: https://meet.google.com/abc-defg-hij\n" +
+				"         Location: Room 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			tt.sink.out = out
+			require.NoError(t, tt.sink.Send(n))
+			assert.Equal(t, tt.want, ts.ReplaceAllString(out.String(), ""))
+		})
+	}
+}
+
+func TestNewAppendsToOutput(t *testing.T) {
+	prev := config.Cfg.Terminal
+	config.Cfg.Terminal = config.Terminal{Output: filepath.Join(t.TempDir(), "notifications.log")}
+	t.Cleanup(func() { config.Cfg.Terminal = prev })
+
+	// the output is created if missing and appended to otherwise
+	for _, summary := range []string{"Standup", "Retro"} {
+		s, err := New()
+		require.NoError(t, err)
+		require.NoError(t, s.Send(&events.Notification{Summary: summary, Event: &events.Event{}}))
+	}
+	b, err := os.ReadFile(config.Cfg.Terminal.Output)
+	require.NoError(t, err)
+	assert.Regexp(t, `^\d\d:\d\d:\d\d Standup\n\d\d:\d\d:\d\d Retro\n$`, string(b))
+	fi, err := os.Stat(config.Cfg.Terminal.Output)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
@@ -0,0 +1,169 @@
+// Package webhook delivers notifications to HTTP endpoints with templated
+// payloads.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+// backoff is the delay before the first retry, doubled for each further one.
+var backoff = time.Second
+
+const requestTimeout = 10 * time.Second
+
+var funcs = template.FuncMap{
+	// json encodes a value as JSON, e.g. to embed strings in JSON payloads
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+type request struct {
+	id   uint32
+	body []byte
+}
+
+// Sink delivers notifications to a webhook. Requests are sent asynchronously
+// in the order the notifications are delivered.
+type Sink struct {
+	cfg   config.Webhook
+	body  *template.Template
+	queue chan *request
+	out   io.Writer // dry-run output
+}
+
+func New(cfg config.Webhook) (*Sink, error) {
+	body, err := template.New(cfg.Name).Funcs(funcs).Parse(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse body template of webhook %s: %w", cfg.Name, err)
+	}
+	s := &Sink{
+		cfg:   cfg,
+		body:  body,
+		queue: make(chan *request, 64),
+		out:   os.Stdout,
+	}
+	return s, nil
+}
+
+// Run sends the queued requests until ctx is done.
+func (s *Sink) Run(ctx context.Context) error {
+	for {
+		select {
+		case req := <-s.queue:
+			if err := s.deliver(ctx, req); err != nil {
+				log.Printf("Failed to deliver notification %d to webhook %s: %v", req.id, s.cfg.Name, err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Sink) Send(n *events.Notification) error {
+	body := new(bytes.Buffer)
+	if err := s.body.Execute(body, n); err != nil {
+		return fmt.Errorf("failed to render body: %w", err)
+	}
+	select {
+	case s.queue <- &request{id: n.ID, body: body.Bytes()}:
+		return nil
+	default:
+		return fmt.Errorf("webhook queue full")
+	}
+}
+
+// Update delivers the changed notification again, as webhooks cannot refer to
+// earlier requests.
+func (s *Sink) Update(n *events.Notification) error {
+	return s.Send(n)
+}
+
+func (s *Sink) Close(id uint32) error {
+	return nil
+}
+
+// deliver sends a request, retrying on network errors, rate limiting and
+// server errors.
+func (s *Sink) deliver(ctx context.Context, req *request) error {
+	if s.cfg.DryRun {
+		s.dump(req)
+		return nil
+	}
+
+	delay := backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.do(ctx, req)
+		if err == nil {
+			config.Debug.Printf("webhook: delivered notification %d to %s", req.id, s.cfg.Name)
+			return nil
+		}
+		if !retry || attempt >= s.cfg.Attempts {
+			return err
+		}
+		config.Debug.Printf("webhook: attempt %d of %s failed, retrying in %v: %v",
+			attempt, s.cfg.Name, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+func (s *Sink) do(ctx context.Context, req *request) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, s.cfg.Method, s.cfg.URL, bytes.NewReader(req.body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range s.cfg.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("request failed with status %s", resp.Status)
+}
+
+// dump prints the request in HTTP/1.1 wire format.
+func (s *Sink) dump(req *request) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", s.cfg.Method, s.cfg.URL)
+	keys := make([]string, 0, len(s.cfg.Headers))
+	for k := range s.cfg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", k, s.cfg.Headers[k])
+	}
+	fmt.Fprintf(&b, "\n%s\n", req.body)
+	io.WriteString(s.out, b.String())
+}
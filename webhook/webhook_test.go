@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/svenschwermer/gcal-notify/config"
+	"github.com/svenschwermer/gcal-notify/events"
+)
+
+func testNotification() *events.Notification {
+	start := time.Date(2030, 5, 12, 14, 0, 0, 0, time.UTC)
+	return &events.Notification{
+		ID:      7,
+		Event:   &events.Event{Summary: `Planning "Q3"`, Start: start, End: start.Add(time.Hour)},
+		Summary: `14:00 | Planning "Q3"`,
+		Body:    "Agenda",
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	prev := backoff
+	backoff = time.Millisecond
+	t.Cleanup(func() { backoff = prev })
+
+	var bodies []string
+	status := []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "high", r.Header.Get("Priority"))
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		rw.WriteHeader(status[len(bodies)-1])
+	}))
+	t.Cleanup(srv.Close)
+
+	s, err := New(config.Webhook{
+		Name:     "ntfy",
+		URL:      srv.URL,
+		Method:   http.MethodPut,
+		Headers:  map[string]string{"Priority": "high"},
+		Body:     `{"title":{{json .Summary}},"start":"{{.Event.Start.Format "15:04"}}"}`,
+		Attempts: 3,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Send(testNotification()))
+	require.NoError(t, s.deliver(context.Background(), <-s.queue))
+	require.Len(t, bodies, 3)
+	assert.Equal(t, `{"title":"14:00 | Planning \"Q3\"","start":"14:00"}`, bodies[2])
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests++
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(srv.Close)
+
+	s, err := New(config.Webhook{Name: "test", URL: srv.URL, Method: http.MethodPost, Attempts: 3})
+	require.NoError(t, err)
+	require.NoError(t, s.Send(testNotification()))
+	err = s.deliver(context.Background(), <-s.queue)
+	assert.EqualError(t, err, "request failed with status 400 Bad Request")
+	assert.Equal(t, 1, requests, "client errors are not retried")
+}
+
+func TestDryRun(t *testing.T) {
+	s, err := New(config.Webhook{
+		Name:    "gotify",
+		URL:     "https://gotify.example.com/message",
+		Method:  http.MethodPost,
+		Headers: map[string]string{"X-Gotify-Key": "secret", "Content-Type": "application/json"},
+		Body:    `{"title":{{json .Summary}},"message":{{json .Body}}}`,
+		DryRun:  true,
+	})
+	require.NoError(t, err)
+	out := new(bytes.Buffer)
+	s.out = out
+
+	require.NoError(t, s.Send(testNotification()))
+	require.NoError(t, s.deliver(context.Background(), <-s.queue))
+	assert.Equal(t, "POST https://gotify.example.com/message\n"+
+		"Content-Type: application/json\n"+
+		"X-Gotify-Key: secret\n"+
+		"\n"+
+		`{"title":"14:00 | Planning \"Q3\"","message":"Agenda"}`+"\n", out.String())
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	_, err := New(config.Webhook{Name: "broken", Body: "{{.Summary"})
+	assert.Error(t, err)
+}